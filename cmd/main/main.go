@@ -2,13 +2,31 @@ package main
 
 import (
 	"Intermediate_web3/internal/api"
+	"Intermediate_web3/internal/config"
 	"Intermediate_web3/internal/database"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/outbox"
+	"Intermediate_web3/internal/rules"
 	"Intermediate_web3/internal/service"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/supervisor"
+	"Intermediate_web3/internal/telegrambot"
+	"Intermediate_web3/internal/tokenmeta"
+	"Intermediate_web3/internal/watchlist"
+	"context"
+	"flag"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 30 * time.Second
+
 func init() {
 	err := godotenv.Load(".env")
 	if err != nil {
@@ -17,28 +35,87 @@ func init() {
 }
 
 func main() {
-	err := database.Connect()
+	startBlock := flag.String("start-block", "", "block to start tracking from, overriding the stored checkpoint, as chain=block pairs separated by commas")
+	migrate := flag.Bool("migrate", false, "apply pending database migrations before starting")
+	configPath := flag.String("config", "config.json", "path of the config file, reloaded when it changes")
+	flag.Parse()
+
+	startBlocks, err := service.ParseStartBlocks(*startBlock)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	err = run(*configPath, startBlocks, *migrate)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, startBlocks map[string]uint64, migrate bool) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("invalid config %s: %w", configPath, err)
+	}
+
+	err = database.Connect()
 	if err != nil {
-		fmt.Printf("Error connecting to the api")
-		return
+		return fmt.Errorf("error connecting to the database: %w", err)
 	}
 	defer database.Close()
 
-	router := gin.Default()
-	err = api.RegisterApi(router)
+	if migrate {
+		err = database.Migrate(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	trackingStore := store.NewPostgres(database.GetDB())
+	registry := watchlist.New()
+	notifiers, err := notify.NewRegistry(cfg.Notifiers)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
-	err = router.Run()
+	alertRules, err := trackingStore.GetAlertRules(context.Background())
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
+	ruleEngine := rules.NewEngine(alertRules)
+	tracker := service.NewTracker(cfg, trackingStore, registry, tokenmeta.NewService(trackingStore), notifiers, ruleEngine)
 
-	err = service.TokenTracking()
+	router := gin.Default()
+	err = api.RegisterApi(router, trackingStore, registry, ruleEngine, notifiers, tracker)
 	if err != nil {
-		return
+		return err
 	}
+	server := &http.Server{
+		Addr:    listenAddr(),
+		Handler: router,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	components := []supervisor.Component{
+		{Name: "http", Run: supervisor.HTTPServer(server, shutdownTimeout)},
+		{Name: "tracker", Run: func(ctx context.Context) error {
+			return tracker.TokenTracking(ctx, startBlocks)
+		}},
+		{Name: "outbox", Run: outbox.NewDispatcher(trackingStore, notifiers).Run},
+		{Name: "config", Run: func(ctx context.Context) error {
+			return config.Watch(ctx, configPath, cfg, tracker.Reload)
+		}},
+		{Name: "bots", Run: telegrambot.NewManager(trackingStore, registry, tracker, notifiers).Run},
+	}
+	return supervisor.Run(ctx, components...)
+}
 
+// listenAddr mirrors the address resolution of gin's router.Run.
+func listenAddr() string {
+	port := os.Getenv("PORT")
+	if port == "" {
+		return ":8080"
+	}
+	return ":" + port
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"Intermediate_web3/internal/database"
+	"context"
+	"fmt"
+	"github.com/joho/godotenv"
+	"os"
+)
+
+const usage = "usage: migrate up|down|status"
+
+func init() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		fmt.Println("Error loading .env file")
+	}
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	err := run(os.Args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(command string) error {
+	err := database.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to the database: %w", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		return database.Migrate(ctx)
+	case "down":
+		return database.Rollback(ctx)
+	case "status":
+		return database.MigrationStatus(ctx)
+	default:
+		return fmt.Errorf("unknown command %q, %s", command, usage)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/store"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportBatchSize is how many transfers are read from the store at a time
+// while exporting.
+const exportBatchSize = 500
+
+var exportHeader = []string{
+	"id", "chain", "transactionHash", "logIndex", "type", "token", "symbol",
+	"from", "fromLabel", "to", "toLabel", "amount", "amountFormatted", "decimals",
+	"blockNumber", "blockHash", "blockTime", "transactionIndex", "gasUsed",
+	"effectiveGasPrice", "fee", "status",
+}
+
+// ExportTracking streams every transfer matching the filters of GetTracking as
+// CSV or NDJSON, depending on the format query parameter. The transfers are
+// read from the store in batches, so the export is never held in memory.
+func (h *Handler) ExportTracking(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "format must be csv or ndjson",
+		})
+		return
+	}
+	filter, err := getTrackingFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+	filter.Limit = exportBatchSize
+
+	// read the first batch before answering, so a failing store is reported
+	batch, err := h.store.QueryTracking(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var write func(trackingInfo models.TrackingInformation) error
+	var flush func() error
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="tracking.csv"`)
+		writer := csv.NewWriter(c.Writer)
+		write = func(trackingInfo models.TrackingInformation) error {
+			return writer.Write(exportRecord(trackingInfo))
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		err = writer.Write(exportHeader)
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="tracking.ndjson"`)
+		encoder := json.NewEncoder(c.Writer)
+		write = func(trackingInfo models.TrackingInformation) error {
+			return encoder.Encode(TrackingResponse{
+				TrackingInformation: trackingInfo,
+				AmountFormatted:     trackingInfo.Amount.Format(trackingInfo.Decimals),
+			})
+		}
+		flush = func() error { return nil }
+	}
+	c.Status(http.StatusOK)
+
+	for err == nil {
+		for _, trackingInfo := range batch {
+			err = write(trackingInfo)
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = flush()
+		}
+		if err != nil || len(batch) < exportBatchSize {
+			break
+		}
+		c.Writer.Flush()
+
+		cursor := store.CursorOf(batch[len(batch)-1], filter)
+		filter.After = &cursor
+		batch, err = h.store.QueryTracking(c.Request.Context(), filter)
+	}
+	if err != nil {
+		// the status is already sent, the export can only be cut short
+		log.Printf("Error during export: %v", err)
+	}
+}
+
+func exportRecord(trackingInfo models.TrackingInformation) []string {
+	blockTime := ""
+	if !trackingInfo.BlockTime.IsZero() {
+		blockTime = trackingInfo.BlockTime.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		strconv.Itoa(trackingInfo.ID),
+		trackingInfo.Chain,
+		trackingInfo.TransactionHash,
+		strconv.Itoa(trackingInfo.LogIndex),
+		trackingInfo.Type,
+		trackingInfo.Token,
+		trackingInfo.Symbol,
+		trackingInfo.From,
+		trackingInfo.FromLabel,
+		trackingInfo.To,
+		trackingInfo.ToLabel,
+		trackingInfo.Amount.String(),
+		trackingInfo.Amount.Format(trackingInfo.Decimals),
+		strconv.Itoa(int(trackingInfo.Decimals)),
+		strconv.FormatUint(trackingInfo.BlockNumber, 10),
+		trackingInfo.BlockHash,
+		blockTime,
+		strconv.FormatUint(uint64(trackingInfo.TransactionIndex), 10),
+		strconv.FormatUint(trackingInfo.GasUsed, 10),
+		trackingInfo.EffectiveGasPrice.String(),
+		trackingInfo.Fee.String(),
+		trackingInfo.Status,
+	}
+}
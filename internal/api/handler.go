@@ -1,15 +1,18 @@
 package api
 
 import (
-	"Intermediate_web3/internal/database"
 	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/rules"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/watchlist"
 	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 )
 
 type Response struct {
@@ -18,42 +21,70 @@ type Response struct {
 	Data    interface{} `json:"Data,omitempty"`
 }
 
+// TrackingResponse is a tracked transfer with its amount made human-readable.
+type TrackingResponse struct {
+	models.TrackingInformation
+	AmountFormatted string `json:"amountFormatted"`
+}
+
 const (
 	defaultPage     = 1
 	defaultPageSize = 10
 )
 
-var (
-	tracking []models.TrackingInformation
-	ctx      = context.Background()
-)
+// Handler serves the API from a TrackingStore. Changes to the watched wallets
+// and tokens, and to the alert rules, are applied to the running trackers
+// too.
+type Handler struct {
+	store     store.TrackingStore
+	watchlist *watchlist.Registry
+	rules     *rules.Engine
+	notifiers *notify.Registry
+	tokens    TokenResolver
+}
+
+// TokenResolver resolves the metadata of the tokens of the tracked chains,
+// see service.Tracker.ResolveToken.
+type TokenResolver interface {
+	ResolveToken(ctx context.Context, chain string, address string) (models.Token, error)
+}
+
+func NewHandler(trackingStore store.TrackingStore, registry *watchlist.Registry, ruleEngine *rules.Engine, notifiers *notify.Registry, tokens TokenResolver) *Handler {
+	return &Handler{store: trackingStore, watchlist: registry, rules: ruleEngine, notifiers: notifiers, tokens: tokens}
+}
 
-func SaveDB(trackingInfo *models.TrackingInformation) error {
-	_, err := database.GetDB().NewInsert().Model(trackingInfo).Exec(ctx)
+// GetTracking lists the tracked transfers matching the filters of
+// getTrackingFilter. Pages are read either with page and pageSize, or by
+// passing the nextCursor of the previous page as cursor, which stays stable
+// while new transfers are stored.
+func (h *Handler) GetTracking(c *gin.Context) {
+	page, pageSize := getPageAndSize(c, defaultPage, defaultPageSize)
+	filter, err := getTrackingFilter(c)
 	if err != nil {
-		fmt.Println("Error inserting data into database:", err)
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
 	}
-	return nil
-}
 
-func GetTracking(c *gin.Context) {
-	// Check for database connection
-	if database.GetDB() == nil {
+	totalRecords, err := h.store.CountTracking(c.Request.Context(), filter)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Status:  "false",
-			Message: "Database connection is not initialized",
+			Message: err.Error(),
 		})
 		return
 	}
-	page, pageSize := getPageAndSize(c, defaultPage, defaultPageSize)
-
-	totalRecords, err := database.GetDB().NewSelect().
-		Model((*models.TrackingInformation)(nil)).
-		Count(ctx)
 
 	totalPages := (totalRecords + pageSize - 1) / pageSize
 
-	tracking, err = GetPaginatedTracking(ctx, page, pageSize)
+	// one more transfer than requested tells whether there is a next page
+	filter.Limit = pageSize + 1
+	if filter.After == nil {
+		filter.Offset = (page - 1) * pageSize
+	}
+	tracking, err := h.store.QueryTracking(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Status:  "false",
@@ -61,16 +92,25 @@ func GetTracking(c *gin.Context) {
 		})
 		return
 	}
+	nextCursor := ""
+	if len(tracking) > pageSize {
+		tracking = tracking[:pageSize]
+		nextCursor = store.CursorOf(tracking[pageSize-1], filter).Encode()
+	}
 
 	c.JSON(http.StatusOK, Response{
 		Status:  "true",
 		Message: "Get all tracking successfully!",
 		Data: struct {
-			TotalPages int                          `json:"totalPages"`
-			Tracking   []models.TrackingInformation `json:"tracking"`
+			TotalRecords int                `json:"totalRecords"`
+			TotalPages   int                `json:"totalPages"`
+			NextCursor   string             `json:"nextCursor,omitempty"`
+			Tracking     []TrackingResponse `json:"tracking"`
 		}{
-			TotalPages: totalPages,
-			Tracking:   tracking,
+			TotalRecords: totalRecords,
+			TotalPages:   totalPages,
+			NextCursor:   nextCursor,
+			Tracking:     toTrackingResponses(tracking),
 		},
 	})
 }
@@ -87,75 +127,119 @@ func getPageAndSize(c *gin.Context, defaultPage, defaultPageSize int) (int, int)
 	return page, pageSize
 }
 
-func GetPaginatedTracking(ctx context.Context, page int, pageSize int) ([]models.TrackingInformation, error) {
-	offset := (page - 1) * pageSize
-
-	err := database.GetDB().NewSelect().
-		Model(&tracking).
-		Limit(pageSize).
-		Offset(offset).
-		Scan(ctx)
-
-	if err != nil {
-		return nil, err
+// getTrackingFilter reads the filters of the query string, all combined with
+// AND:
+//   - chain, type, token, symbol, from, to, address (either party) and
+//     transactionHash, matched exactly, type and symbol case-insensitively
+//   - minAmount and maxAmount, raw integer amounts in the smallest unit of the
+//     token
+//   - fromBlock and toBlock, inclusive block numbers
+//   - fromTime and toTime, RFC 3339 timestamps, toTime exclusive
+//
+// and the order of the listing: sort (id, blockNumber, blockTime or amount),
+// order (asc or desc) and cursor.
+func getTrackingFilter(c *gin.Context) (store.TrackingFilter, error) {
+	var filter store.TrackingFilter
+	for param, value := range map[string]*string{
+		"chain":           &filter.Chain,
+		"type":            &filter.Type,
+		"token":           &filter.Token,
+		"symbol":          &filter.Symbol,
+		"from":            &filter.From,
+		"to":              &filter.To,
+		"address":         &filter.Address,
+		"transactionHash": &filter.TransactionHash,
+	} {
+		*value = c.Query(param)
 	}
-
-	return tracking, nil
-}
-
-func GetTrackingByKey(c *gin.Context) {
-	if database.GetDB() == nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "false",
-			Message: "Database connection is not initialized",
-		})
-		return
+	for param, bound := range map[string]**models.BigInt{
+		"minAmount": &filter.MinAmount,
+		"maxAmount": &filter.MaxAmount,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		amount, err := models.ParseBigInt(value)
+		if err != nil {
+			return store.TrackingFilter{}, fmt.Errorf("%s must be a raw integer amount", param)
+		}
+		*bound = &amount
 	}
-	query := database.GetDB().NewSelect().Model(&tracking)
-
-	if c.Query("type") != "" {
-		query = query.Where(`LOWER("type") = ?`, strings.ToLower(c.Query("type")))
+	for param, bound := range map[string]**uint64{
+		"fromBlock": &filter.FromBlock,
+		"toBlock":   &filter.ToBlock,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return store.TrackingFilter{}, fmt.Errorf("%s must be a block number", param)
+		}
+		*bound = &number
 	}
-
-	if c.Query("symbol") != "" {
-		query = query.WhereOr(`LOWER("symbol") = ?`, strings.ToLower(c.Query("symbol")))
+	for param, bound := range map[string]**time.Time{
+		"fromTime": &filter.FromTime,
+		"toTime":   &filter.ToTime,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return store.TrackingFilter{}, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+		}
+		*bound = &t
 	}
 
-	err := query.Scan(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "false",
-			Message: "Error getting tracking information",
-		})
-		return
+	filter.Sort = c.DefaultQuery("sort", store.SortID)
+	if !store.ValidSort(filter.Sort) {
+		return store.TrackingFilter{}, fmt.Errorf("sort must be one of id, blockNumber, blockTime or amount")
 	}
-
-	if len(tracking) == 0 {
-		c.JSON(http.StatusNotFound, Response{
-			Status:  "false",
-			Message: "No tracking found with the provided type token value",
-		})
-		return
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return store.TrackingFilter{}, fmt.Errorf("order must be asc or desc")
 	}
-
-	c.JSON(http.StatusOK, Response{
-		Status:  "true",
-		Message: "Found tracking successfully!",
-		Data:    tracking,
-	})
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := store.DecodeCursor(value)
+		if err != nil {
+			return store.TrackingFilter{}, err
+		}
+		// the cursor carries the order of the listing it was taken from
+		if (c.Query("sort") != "" && cursor.Sort != filter.Sort) || (c.Query("order") != "" && cursor.Desc != filter.Desc) {
+			return store.TrackingFilter{}, fmt.Errorf("cursor was made for another sort order")
+		}
+		filter.Sort, filter.Desc = cursor.Sort, cursor.Desc
+		filter.After = &cursor
+	}
+	return filter, nil
 }
 
-func DeleteTrackingTransaction(c *gin.Context) {
-	// Check for database connection
-	if database.GetDB() == nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "false",
-			Message: "Database connection is not initialized",
+func toTrackingResponses(tracking []models.TrackingInformation) []TrackingResponse {
+	responses := make([]TrackingResponse, 0, len(tracking))
+	for _, trackingInfo := range tracking {
+		responses = append(responses, TrackingResponse{
+			TrackingInformation: trackingInfo,
+			AmountFormatted:     trackingInfo.Amount.Format(trackingInfo.Decimals),
 		})
-		return
 	}
+	return responses
+}
 
-	transactionToDelete := strings.ToLower(c.Query("transaction"))
+// DeleteTrackingTransaction deletes the transfers of the transaction given in
+// the path, or in the transaction query parameter which older clients send
+// along with any path.
+func (h *Handler) DeleteTrackingTransaction(c *gin.Context) {
+	transactionToDelete := c.Query("transaction")
+	if transactionToDelete == "" {
+		transactionToDelete = c.Param("transaction")
+	}
 	if transactionToDelete == "" {
 		c.JSON(http.StatusInternalServerError, Response{
 			Status:  "false",
@@ -164,13 +248,7 @@ func DeleteTrackingTransaction(c *gin.Context) {
 		return
 	}
 
-	query := database.GetDB().
-		NewDelete().
-		Model(&models.TrackingInformation{}).
-		Where(`LOWER("transactionHash") = ?`, transactionToDelete)
-
-	// Execute the query
-	res, err := query.Exec(context.Background())
+	rowsAffected, err := h.store.DeleteTracking(c.Request.Context(), transactionToDelete)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Status:  "false",
@@ -179,7 +257,6 @@ func DeleteTrackingTransaction(c *gin.Context) {
 		log.Printf("Error during delete: %v", err)
 		return
 	}
-	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
 		c.JSON(http.StatusNotFound, Response{
 			Status:  "false",
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/rules"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/tokenmeta"
+	"Intermediate_web3/internal/watchlist"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// newTestServer serves the API from a memory store holding transfers of
+// count transactions, 0x0 to 0x<count-1> in hex, with two transfers each.
+func newTestServer(t *testing.T, count int) (*httptest.Server, *store.Memory) {
+	gin.SetMode(gin.TestMode)
+	trackingStore := store.NewMemory()
+	for i := 0; i < count; i++ {
+		for logIndex := 0; logIndex < 2; logIndex++ {
+			_, err := trackingStore.SaveTracking(context.Background(), &models.TrackingInformation{
+				Chain:           "eth",
+				TransactionHash: fmt.Sprintf("0x%x", i),
+				LogIndex:        logIndex,
+				Amount:          models.NewBigInt(big.NewInt(int64(i))),
+				BlockNumber:     uint64(i),
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	notifiers, err := notify.NewRegistry(map[string]models.NotifierConfig{
+		"ops": {Type: "webhook", URL: "http://127.0.0.1:1/alerts"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := watchlist.New()
+	registry.SetConfig(models.ChainConfig{Chain: "eth"})
+	router := gin.New()
+	err = RegisterApi(router, trackingStore, registry, rules.NewEngine(nil), notifiers, fakeTokens{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, trackingStore
+}
+
+// notToken and unreachableToken are addresses fakeTokens fails to resolve.
+const (
+	notToken         = "0x00000000000000000000000000000000000000dd"
+	unreachableToken = "0x00000000000000000000000000000000000000ee"
+)
+
+// fakeTokens resolves every address as a token but notToken and
+// unreachableToken.
+type fakeTokens struct{}
+
+func (fakeTokens) ResolveToken(ctx context.Context, chain string, address string) (models.Token, error) {
+	switch address {
+	case notToken:
+		return models.Token{}, fmt.Errorf("failed to get token decimals: %w", tokenmeta.ErrNotToken)
+	case unreachableToken:
+		return models.Token{}, fmt.Errorf("chain %s is not connected", chain)
+	}
+	return models.Token{Chain: chain, Address: address, Symbol: "TKN", Decimals: 6}, nil
+}
+
+func do(method, url string) (*http.Response, error) {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(request)
+}
+
+func TestConcurrentDeletes(t *testing.T) {
+	const transactions = 50
+	server, trackingStore := newTestServer(t, transactions)
+
+	// every transaction is deleted twice at once, by path and by the query
+	// parameter of older clients: exactly one of them finds it
+	statuses := make([][2]int, transactions)
+	var wg sync.WaitGroup
+	for i := 0; i < transactions; i++ {
+		hash := fmt.Sprintf("0x%x", i)
+		for j, url := range []string{
+			server.URL + "/tracking/" + hash,
+			server.URL + "/tracking/any?transaction=" + hash,
+		} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response, err := do(http.MethodDelete, url)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				response.Body.Close()
+				statuses[i][j] = response.StatusCode
+			}()
+		}
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		ok, notFound := 0, 0
+		for _, code := range status {
+			switch code {
+			case http.StatusOK:
+				ok++
+			case http.StatusNotFound:
+				notFound++
+			}
+		}
+		if ok != 1 || notFound != 1 {
+			t.Errorf("deletes of 0x%x answered %v, want one 200 and one 404", i, status)
+		}
+	}
+	count, _ := trackingStore.CountTracking(context.Background(), store.TrackingFilter{})
+	if count != 0 {
+		t.Errorf("%d transfers left, want 0", count)
+	}
+}
+
+func TestConcurrentReadsDuringDeletes(t *testing.T) {
+	const transactions = 400
+	server, _ := newTestServer(t, transactions)
+
+	var wg sync.WaitGroup
+	for i := 0; i < transactions; i += 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := do(http.MethodDelete, server.URL+fmt.Sprintf("/tracking?transaction=0x%x", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("delete of 0x%x answered %d, want 200", i, response.StatusCode)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			checkPage(t, server.URL+"/tracking?pageSize=20&sort=amount&order=desc")
+		}()
+		go func() {
+			defer wg.Done()
+			checkExport(t, server.URL+"/tracking/export?format=csv", transactions)
+		}()
+	}
+	wg.Wait()
+
+	// the odd transactions were never deleted
+	checkExport(t, server.URL+"/tracking/export?format=csv", transactions)
+}
+
+// checkPage checks that a page of GetTracking is complete and in order.
+func checkPage(t *testing.T, url string) {
+	response, err := do(http.MethodGet, url)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer response.Body.Close()
+	var body struct {
+		Status string
+		Data   struct {
+			TotalRecords int                `json:"totalRecords"`
+			Tracking     []TrackingResponse `json:"tracking"`
+		}
+	}
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		t.Errorf("failed to decode page: %v", err)
+		return
+	}
+	if response.StatusCode != http.StatusOK || body.Status != "true" {
+		t.Errorf("GET answered %d %s, want 200 true", response.StatusCode, body.Status)
+		return
+	}
+	if len(body.Data.Tracking) != min(20, body.Data.TotalRecords) {
+		t.Errorf("page has %d transfers of %d, want 20", len(body.Data.Tracking), body.Data.TotalRecords)
+	}
+	for i := 1; i < len(body.Data.Tracking); i++ {
+		if body.Data.Tracking[i].Amount.Compare(body.Data.Tracking[i-1].Amount) > 0 {
+			t.Errorf("page is not sorted by descending amount")
+			return
+		}
+	}
+}
+
+// checkExport checks that a CSV export lists the two transfers of every odd
+// transaction below transactions, which are never deleted, and lists each
+// transfer once and in order whatever is deleted meanwhile.
+func checkExport(t *testing.T, url string, transactions int) {
+	response, err := do(http.MethodGet, url)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("export answered %d, want 200", response.StatusCode)
+		return
+	}
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil {
+		t.Errorf("failed to read export: %v", err)
+		return
+	}
+	if len(records) == 0 || records[0][0] != "id" {
+		t.Error("export has no header")
+		return
+	}
+	lastID := 0
+	odd := 0
+	for _, record := range records[1:] {
+		id, _ := strconv.Atoi(record[0])
+		if id <= lastID {
+			t.Errorf("export lists transfer %d after %d", id, lastID)
+			return
+		}
+		lastID = id
+		number, _ := strconv.ParseUint(record[2][2:], 16, 64)
+		if number%2 == 1 {
+			odd++
+		}
+	}
+	if odd != transactions {
+		t.Errorf("export has %d transfers of odd transactions, want %d", odd, transactions)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"Intermediate_web3/internal/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// GetOutbox lists the queued alerts, newest first, optionally only those
+// with status (pending, delivered or dead), with page and pageSize.
+func (h *Handler) GetOutbox(c *gin.Context) {
+	page, pageSize := getPageAndSize(c, defaultPage, defaultPageSize)
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboxPending, models.OutboxDelivered, models.OutboxDead:
+	default:
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "invalid status " + status,
+		})
+		return
+	}
+
+	totalRecords, err := h.store.CountOutbox(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+	messages, err := h.store.QueryOutbox(c.Request.Context(), status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Get outbox successfully!",
+		Data: struct {
+			TotalRecords int                    `json:"totalRecords"`
+			TotalPages   int                    `json:"totalPages"`
+			Messages     []models.OutboxMessage `json:"messages"`
+		}{
+			TotalRecords: totalRecords,
+			TotalPages:   (totalRecords + pageSize - 1) / pageSize,
+			Messages:     messages,
+		},
+	})
+}
+
+// RetryOutbox queues the alert of the path again, e.g. a dead one once its
+// notifier is fixed.
+func (h *Handler) RetryOutbox(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "invalid id " + c.Param("id"),
+		})
+		return
+	}
+	found, err := h.store.RetryOutbox(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: "Error retrying message",
+		})
+		log.Printf("Error during retry: %v", err)
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, Response{
+			Status:  "false",
+			Message: "No message found with the provided id",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Queued message again successfully!",
+	})
+}
@@ -1,16 +1,49 @@
 package api
 
 import (
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/rules"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/watchlist"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterApi(router *gin.Engine) error {
+func RegisterApi(router *gin.Engine, trackingStore store.TrackingStore, registry *watchlist.Registry, ruleEngine *rules.Engine, notifiers *notify.Registry, tokens TokenResolver) error {
+	handler := NewHandler(trackingStore, registry, ruleEngine, notifiers, tokens)
+
 	// Register service routes
 	trackingGroup := router.Group("/tracking")
 	{
-		trackingGroup.GET("", GetTracking)
-		trackingGroup.GET("/:search", GetTrackingByKey)
-		trackingGroup.DELETE("/:transaction", DeleteTrackingTransaction)
+		trackingGroup.GET("", handler.GetTracking)
+		trackingGroup.GET("/export", handler.ExportTracking)
+		trackingGroup.DELETE("", handler.DeleteTrackingTransaction)
+		trackingGroup.DELETE("/:transaction", handler.DeleteTrackingTransaction)
+	}
+	walletGroup := router.Group("/wallets")
+	{
+		walletGroup.GET("", handler.GetWallets)
+		walletGroup.POST("", handler.CreateWallet)
+		walletGroup.PUT("/:chain/:address", handler.UpdateWallet)
+		walletGroup.DELETE("/:chain/:address", handler.DeleteWallet)
+	}
+	tokenGroup := router.Group("/tokens")
+	{
+		tokenGroup.GET("", handler.GetTokens)
+		tokenGroup.POST("", handler.CreateToken)
+		tokenGroup.PUT("/:chain/:address", handler.UpdateToken)
+		tokenGroup.DELETE("/:chain/:address", handler.DeleteToken)
+	}
+	outboxGroup := router.Group("/outbox")
+	{
+		outboxGroup.GET("", handler.GetOutbox)
+		outboxGroup.POST("/:id/retry", handler.RetryOutbox)
+	}
+	ruleGroup := router.Group("/rules")
+	{
+		ruleGroup.GET("", handler.GetAlertRules)
+		ruleGroup.POST("", handler.CreateAlertRule)
+		ruleGroup.PUT("/:id", handler.UpdateAlertRule)
+		ruleGroup.DELETE("/:id", handler.DeleteAlertRule)
 	}
 	return nil
 }
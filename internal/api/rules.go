@@ -0,0 +1,197 @@
+package api
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/rules"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (h *Handler) GetAlertRules(c *gin.Context) {
+	alertRules, err := h.store.GetAlertRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Get alert rules successfully!",
+		Data:    alertRules,
+	})
+}
+
+func (h *Handler) CreateAlertRule(c *gin.Context) {
+	var rule models.AlertRule
+	err := c.ShouldBindJSON(&rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	err = h.normalizeAlertRule(&rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err = h.store.CreateAlertRule(c.Request.Context(), &rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: "Error saving alert rule",
+		})
+		log.Printf("Error during save: %v", err)
+		return
+	}
+	h.rules.Put(rule)
+
+	c.JSON(http.StatusCreated, Response{
+		Status:  "true",
+		Message: "Added alert rule successfully!",
+		Data:    rule,
+	})
+}
+
+func (h *Handler) UpdateAlertRule(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+	var rule models.AlertRule
+	err := c.ShouldBindJSON(&rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	rule.ID = id
+	err = h.normalizeAlertRule(&rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	found, err := h.store.UpdateAlertRule(c.Request.Context(), &rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: "Error saving alert rule",
+		})
+		log.Printf("Error during save: %v", err)
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, Response{
+			Status:  "false",
+			Message: "No alert rule found with the provided id",
+		})
+		return
+	}
+	h.rules.Put(rule)
+
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Updated alert rule successfully!",
+		Data:    rule,
+	})
+}
+
+func (h *Handler) DeleteAlertRule(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+	deleted, err := h.store.DeleteAlertRule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: "Error deleting alert rule",
+		})
+		log.Printf("Error during delete: %v", err)
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, Response{
+			Status:  "false",
+			Message: "No alert rule found with the provided id",
+		})
+		return
+	}
+	h.rules.Remove(id)
+
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Deleted alert rule successfully!",
+	})
+}
+
+func alertRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "invalid id " + c.Param("id"),
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// normalizeAlertRule checks the conditions and notifiers of rule, lowercases
+// its addresses and defaults its severity to info.
+func (h *Handler) normalizeAlertRule(rule *models.AlertRule) error {
+	if rule.Chain != "" && !h.watchlist.HasChain(rule.Chain) {
+		return fmt.Errorf("unknown chain %s", rule.Chain)
+	}
+	rule.Token = strings.ToLower(rule.Token)
+	if rule.Token != "" && rule.Token != rules.TokenNative && !common.IsHexAddress(rule.Token) {
+		return fmt.Errorf("invalid token %s, expected an address or %s", rule.Token, rules.TokenNative)
+	}
+	switch rule.Direction {
+	case "", notify.DirectionIncoming, notify.DirectionOutgoing, notify.DirectionInternal:
+	default:
+		return fmt.Errorf("invalid direction %s", rule.Direction)
+	}
+	rule.Counterparty = strings.ToLower(rule.Counterparty)
+	if rule.Counterparty != "" && !common.IsHexAddress(rule.Counterparty) {
+		return fmt.Errorf("invalid counterparty %s", rule.Counterparty)
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && rule.MinAmount.Compare(*rule.MaxAmount) > 0 {
+		return errors.New("minAmount is above maxAmount")
+	}
+	if rule.MinUSD != nil && rule.MaxUSD != nil && *rule.MinUSD > *rule.MaxUSD {
+		return errors.New("minUSD is above maxUSD")
+	}
+	for _, name := range rule.Notifiers {
+		if _, ok := h.notifiers.Get(name); !ok {
+			return fmt.Errorf("unknown notifier %s", name)
+		}
+	}
+	if rule.Severity == "" {
+		rule.Severity = models.SeverityInfo
+	}
+	if !rules.ValidSeverity(rule.Severity) {
+		return fmt.Errorf("invalid severity %s", rule.Severity)
+	}
+	return nil
+}
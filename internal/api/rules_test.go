@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func postRule(t *testing.T, url string, body string) (int, map[string]interface{}) {
+	response, err := http.Post(url+"/rules", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var decoded struct {
+		Message string
+		Data    map[string]interface{}
+	}
+	json.NewDecoder(response.Body).Decode(&decoded)
+	if decoded.Data == nil {
+		decoded.Data = map[string]interface{}{"message": decoded.Message}
+	}
+	return response.StatusCode, decoded.Data
+}
+
+func TestCreateAlertRuleChecksNotifiers(t *testing.T) {
+	server, _ := newTestServer(t, 0)
+	status, data := postRule(t, server.URL, `{"name": "big", "notifiers": ["ops", "missing"]}`)
+	if status != http.StatusBadRequest || data["message"] != "unknown notifier missing" {
+		t.Errorf("rule with an unknown notifier answered %d %v, want 400", status, data)
+	}
+	status, _ = postRule(t, server.URL, `{"name": "big", "notifiers": ["ops"]}`)
+	if status != http.StatusCreated {
+		t.Errorf("rule with a known notifier answered %d, want 201", status)
+	}
+}
+
+func TestCreateAlertRuleKeepsAmountsExact(t *testing.T) {
+	server, _ := newTestServer(t, 0)
+	status, data := postRule(t, server.URL, `{"name": "band", "minAmount": "0.000000000000000001", "maxAmount": 12345678901234567890.5}`)
+	if status != http.StatusCreated {
+		t.Fatalf("answered %d %v, want 201", status, data)
+	}
+	if data["minAmount"] != "0.000000000000000001" || data["maxAmount"] != "12345678901234567890.5" {
+		t.Errorf("amounts = %v and %v, want them exact", data["minAmount"], data["maxAmount"])
+	}
+	status, _ = postRule(t, server.URL, `{"name": "inverted", "minAmount": "2", "maxAmount": "1.5"}`)
+	if status != http.StatusBadRequest {
+		t.Errorf("inverted amounts answered %d, want 400", status)
+	}
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/tokenmeta"
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// labelledRequest is the body of the requests adding a wallet or a token. The
+// chain and address come from the path when updating one.
+type labelledRequest struct {
+	Chain   string `json:"chain"`
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+// labelledList is what the wallet and token endpoints share: the stored list,
+// and the watchlist entries the running trackers read.
+type labelledList struct {
+	name   string
+	save   func(ctx context.Context, chain, address, label string) (interface{}, bool, error)
+	delete func(ctx context.Context, chain, address string) (bool, error)
+	set    func(chain, address, label string)
+	remove func(chain, address string)
+	// check, if set, vets an address before it is saved.
+	check func(ctx context.Context, chain, address string) error
+}
+
+func (h *Handler) walletList() labelledList {
+	return labelledList{
+		name: "wallet",
+		save: func(ctx context.Context, chain, address, label string) (interface{}, bool, error) {
+			wallet := &models.Wallet{Chain: chain, Address: address, Label: label}
+			created, err := h.store.SaveWallet(ctx, wallet)
+			return wallet, created, err
+		},
+		delete: h.store.DeleteWallet,
+		set:    h.watchlist.SetWallet,
+		remove: h.watchlist.RemoveWallet,
+	}
+}
+
+func (h *Handler) tokenList() labelledList {
+	return labelledList{
+		name: "token",
+		save: func(ctx context.Context, chain, address, label string) (interface{}, bool, error) {
+			trackedToken := &models.TrackedToken{Chain: chain, Address: address, Label: label}
+			created, err := h.store.SaveTrackedToken(ctx, trackedToken)
+			return trackedToken, created, err
+		},
+		delete: h.store.DeleteTrackedToken,
+		set:    h.watchlist.SetToken,
+		remove: h.watchlist.RemoveToken,
+		check: func(ctx context.Context, chain, address string) error {
+			_, err := h.tokens.ResolveToken(ctx, chain, address)
+			return err
+		},
+	}
+}
+
+func (h *Handler) GetWallets(c *gin.Context) {
+	wallets, err := h.store.GetWallets(c.Request.Context(), c.Query("chain"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Get wallets successfully!",
+		Data:    wallets,
+	})
+}
+
+func (h *Handler) GetTokens(c *gin.Context) {
+	trackedTokens, err := h.store.GetTrackedTokens(c.Request.Context(), c.Query("chain"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Get tokens successfully!",
+		Data:    trackedTokens,
+	})
+}
+
+func (h *Handler) CreateWallet(c *gin.Context) {
+	h.createLabelled(c, h.walletList())
+}
+
+func (h *Handler) UpdateWallet(c *gin.Context) {
+	h.updateLabelled(c, h.walletList())
+}
+
+func (h *Handler) DeleteWallet(c *gin.Context) {
+	h.deleteLabelled(c, h.walletList())
+}
+
+func (h *Handler) CreateToken(c *gin.Context) {
+	h.createLabelled(c, h.tokenList())
+}
+
+func (h *Handler) UpdateToken(c *gin.Context) {
+	h.updateLabelled(c, h.tokenList())
+}
+
+func (h *Handler) DeleteToken(c *gin.Context) {
+	h.deleteLabelled(c, h.tokenList())
+}
+
+func (h *Handler) createLabelled(c *gin.Context, list labelledList) {
+	var request labelledRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	h.saveLabelled(c, list, request)
+}
+
+// updateLabelled sets the label of the wallet or token of the path, adding
+// it if it is not stored yet.
+func (h *Handler) updateLabelled(c *gin.Context, list labelledList) {
+	var request labelledRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "Invalid request body",
+		})
+		return
+	}
+	request.Chain = c.Param("chain")
+	request.Address = c.Param("address")
+	h.saveLabelled(c, list, request)
+}
+
+func (h *Handler) saveLabelled(c *gin.Context, list labelledList, request labelledRequest) {
+	if !h.validChainAddress(c, request.Chain, request.Address) {
+		return
+	}
+	address := strings.ToLower(request.Address)
+	if list.check != nil {
+		err := list.check(c.Request.Context(), request.Chain, address)
+		if err != nil {
+			status := http.StatusServiceUnavailable
+			if errors.Is(err, tokenmeta.ErrNotToken) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, Response{
+				Status:  "false",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	saved, created, err := list.save(c.Request.Context(), request.Chain, address, request.Label)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: "Error saving " + list.name,
+		})
+		log.Printf("Error during save: %v", err)
+		return
+	}
+	list.set(request.Chain, address, request.Label)
+
+	if created {
+		c.JSON(http.StatusCreated, Response{
+			Status:  "true",
+			Message: "Added " + list.name + " successfully!",
+			Data:    saved,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Updated " + list.name + " successfully!",
+		Data:    saved,
+	})
+}
+
+func (h *Handler) deleteLabelled(c *gin.Context, list labelledList) {
+	chain, address := c.Param("chain"), c.Param("address")
+	if !h.validChainAddress(c, chain, address) {
+		return
+	}
+	address = strings.ToLower(address)
+	deleted, err := list.delete(c.Request.Context(), chain, address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Status:  "false",
+			Message: "Error deleting " + list.name,
+		})
+		log.Printf("Error during delete: %v", err)
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, Response{
+			Status:  "false",
+			Message: "No " + list.name + " found with the provided address",
+		})
+		return
+	}
+	list.remove(chain, address)
+
+	c.JSON(http.StatusOK, Response{
+		Status:  "true",
+		Message: "Deleted " + list.name + " successfully!",
+	})
+}
+
+// validChainAddress checks that chain is a configured chain and address an
+// EVM address, and answers with an error otherwise.
+func (h *Handler) validChainAddress(c *gin.Context, chain string, address string) bool {
+	if !h.watchlist.HasChain(chain) {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "Unknown chain " + chain,
+		})
+		return false
+	}
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, Response{
+			Status:  "false",
+			Message: "Invalid address " + address,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateTokenChecksContract(t *testing.T) {
+	server, trackingStore := newTestServer(t, 0)
+	for address, want := range map[string]int{
+		notToken:         http.StatusBadRequest,
+		unreachableToken: http.StatusServiceUnavailable,
+		"0x00000000000000000000000000000000000000CC": http.StatusCreated,
+	} {
+		body := `{"chain": "eth", "address": "` + address + `"}`
+		response, err := http.Post(server.URL+"/tokens", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != want {
+			t.Errorf("adding token %s answered %d, want %d", address, response.StatusCode, want)
+		}
+	}
+	tokens, _ := trackingStore.GetTrackedTokens(context.Background(), "eth")
+	if len(tokens) != 1 || tokens[0].Address != "0x00000000000000000000000000000000000000cc" {
+		t.Errorf("stored tokens = %+v, want the ERC-20 token alone", tokens)
+	}
+}
@@ -0,0 +1,5 @@
+// Package build holds the contract bindings generated with abigen, at the
+// go-ethereum version of go.mod.
+package build
+
+//go:generate go run github.com/ethereum/go-ethereum/cmd/abigen --abi erc20.abi --pkg build --type Store --out erc20.go
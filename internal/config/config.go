@@ -0,0 +1,139 @@
+package config
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"strings"
+)
+
+// Load reads the config file at path and validates it. Token addresses are
+// lowercased, as the tracker compares them lowercased.
+func Load(path string) (*models.Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	return parse(content)
+}
+
+func parse(content []byte) (*models.Config, error) {
+	var config models.Config
+	err := json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	err = Validate(&config)
+	if err != nil {
+		return nil, err
+	}
+	normalize(&config)
+	return &config, nil
+}
+
+// Validate checks that the chains are unique and can be tracked, that every
+// address is valid, with a valid checksum if it is mixed-case, that every
+// tracked token is configured in TrackingTokensConfig and that the notifiers
+// are valid and the ones named exist. It reports every problem found.
+func Validate(config *models.Config) error {
+	var errs []error
+	if len(config.Chains) == 0 {
+		errs = append(errs, errors.New("no chain configured"))
+	}
+	for name, notifierConfig := range config.Notifiers {
+		_, err := notify.New(notifierConfig)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", name, err))
+		}
+		_, err = notify.NewTemplates(notifierConfig.Format, notifierConfig.Templates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", name, err))
+		}
+	}
+	chains := make(map[string]bool)
+	for i, chainConfig := range config.Chains {
+		name := chainConfig.Chain
+		if name == "" {
+			name = fmt.Sprintf("chains[%d]", i)
+			errs = append(errs, fmt.Errorf("%s: chain name is required", name))
+		} else if chains[name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate chain", name))
+		}
+		chains[name] = true
+
+		if len(chainConfig.Endpoints()) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no RPC endpoint", name))
+		}
+		errs = append(errs, checkNotify(config, name, chainConfig.Notify)...)
+		for _, wallet := range chainConfig.Wallets {
+			err := checkAddress(wallet.Address)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: wallet: %w", name, err))
+			}
+			errs = append(errs, checkNotify(config, name, wallet.Notify)...)
+		}
+		tokenConfigs := make(map[string]bool)
+		for address := range chainConfig.TrackingTokensConfig {
+			err := checkAddress(address)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: trackingTokensConfig: %w", name, err))
+			}
+			tokenConfigs[strings.ToLower(address)] = true
+		}
+		for _, address := range chainConfig.ListTokensTracking {
+			err := checkAddress(address)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: listTokensTracking: %w", name, err))
+				continue
+			}
+			if !tokenConfigs[strings.ToLower(address)] {
+				errs = append(errs, fmt.Errorf("%s: token %s has no trackingTokensConfig entry", name, address))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkNotify checks that the notifiers named by a chain or a wallet are
+// configured.
+func checkNotify(config *models.Config, chain string, names []string) []error {
+	var errs []error
+	for _, name := range names {
+		if _, ok := config.Notifiers[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown notifier %q", chain, name))
+		}
+	}
+	return errs
+}
+
+// checkAddress accepts 0x-prefixed addresses, either all lowercase or
+// uppercase, or mixed-case with a valid EIP-55 checksum.
+func checkAddress(address string) error {
+	digits, found := strings.CutPrefix(address, "0x")
+	if !found || !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) &&
+		common.HexToAddress(address).Hex() != address {
+		return fmt.Errorf("invalid checksum of address %q", address)
+	}
+	return nil
+}
+
+func normalize(config *models.Config) {
+	for i := range config.Chains {
+		chainConfig := &config.Chains[i]
+		tokenConfigs := make(map[string]models.TokenConfig, len(chainConfig.TrackingTokensConfig))
+		for address, tokenConfig := range chainConfig.TrackingTokensConfig {
+			tokenConfigs[strings.ToLower(address)] = tokenConfig
+		}
+		chainConfig.TrackingTokensConfig = tokenConfigs
+		for j, address := range chainConfig.ListTokensTracking {
+			chainConfig.ListTokensTracking[j] = strings.ToLower(address)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// reloadDelay groups the events of a single save, editors often write a file
+// in several steps.
+const reloadDelay = 500 * time.Millisecond
+
+// Watch reloads the config file at path whenever it changes, until ctx is
+// cancelled, and passes every new valid config to apply. current is the
+// config in use. An invalid edit is rejected and logged with its diff to the
+// last valid file, and the config in use is kept.
+func Watch(ctx context.Context, path string, current *models.Config, apply func(*models.Config)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+	defer watcher.Close()
+	// the directory is watched, as editors replace files on save
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+	accepted, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	reload := time.NewTimer(reloadDelay)
+	reload.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(path) &&
+				event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				reload.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Printf("Config watcher error: %v\n", err)
+		case <-reload.C:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				// the file is being replaced, a later event reloads it
+				continue
+			}
+			config, err := parse(content)
+			if err != nil {
+				fmt.Printf("Rejected change of %s: %v\n%s", path, err, lineDiff(string(accepted), string(content)))
+				continue
+			}
+			accepted = content
+			if reflect.DeepEqual(config, current) {
+				continue
+			}
+			fmt.Printf("Reloaded %s\n", path)
+			current = config
+			apply(config)
+		}
+	}
+}
+
+// lineDiff returns the lines removed from before, prefixed with "-", and the
+// lines added in after, prefixed with "+".
+func lineDiff(before, after string) string {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+	// common[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			fmt.Fprintf(&diff, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&diff, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return diff.String()
+}
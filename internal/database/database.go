@@ -1,8 +1,6 @@
 package database
 
 import (
-	"Intermediate_web3/internal/models"
-	"context"
 	"database/sql"
 	"fmt"
 	"github.com/uptrace/bun"
@@ -20,15 +18,7 @@ func Connect() error {
 		return fmt.Errorf("failed to initialize DB")
 	}
 
-	_, err := db.NewCreateTable().
-		Model((*models.TrackingInformation)(nil)).
-		IfNotExists().
-		Exec(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
-
-	err = db.Ping()
+	err := db.Ping()
 	if err != nil {
 		return fmt.Errorf("error pinging the api: %w", err)
 	}
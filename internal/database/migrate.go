@@ -0,0 +1,82 @@
+package database
+
+import (
+	"Intermediate_web3/internal/database/migrations"
+	"context"
+	"fmt"
+	"github.com/uptrace/bun/migrate"
+)
+
+func newMigrator(ctx context.Context) (*migrate.Migrator, error) {
+	migrator := migrate.NewMigrator(db, migrations.Migrations)
+	// creates the bun_migrations and bun_migration_locks tables
+	err := migrator.Init(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+	return migrator, nil
+}
+
+// Migrate applies the pending migrations as a new group.
+func Migrate(ctx context.Context) error {
+	migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	err = migrator.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer migrator.Unlock(ctx)
+
+	group, err := migrator.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	if group.IsZero() {
+		fmt.Println("Database schema is up to date")
+		return nil
+	}
+	fmt.Printf("Migrated to %s\n", group)
+	return nil
+}
+
+// Rollback reverts the last applied group of migrations.
+func Rollback(ctx context.Context) error {
+	migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	err = migrator.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer migrator.Unlock(ctx)
+
+	group, err := migrator.Rollback(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to rollback migrations: %w", err)
+	}
+	if group.IsZero() {
+		fmt.Println("There are no migrations to rollback")
+		return nil
+	}
+	fmt.Printf("Rolled back %s\n", group)
+	return nil
+}
+
+// MigrationStatus prints the applied and pending migrations.
+func MigrationStatus(ctx context.Context) error {
+	migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	ms, err := migrator.MigrationsWithStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migrations: %w", err)
+	}
+	fmt.Printf("Migrations: %s\n", ms)
+	fmt.Printf("Unapplied migrations: %s\n", ms.Unapplied())
+	fmt.Printf("Last migration group: %s\n", ms.LastGroup())
+	return nil
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "tracking" (
+	"id" BIGSERIAL NOT NULL,
+	"transactionHash" VARCHAR NOT NULL,
+	"type" VARCHAR NOT NULL,
+	"from" VARCHAR NOT NULL,
+	"to" VARCHAR NOT NULL,
+	"chain" VARCHAR NOT NULL,
+	"token" VARCHAR,
+	"symbol" VARCHAR,
+	"amount" VARCHAR NOT NULL,
+	PRIMARY KEY ("id")
+);
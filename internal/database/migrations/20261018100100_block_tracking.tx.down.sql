@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS "wallets";
+
+DROP TABLE IF EXISTS "blocks";
+
+DROP TABLE IF EXISTS "checkpoints";
+
+DROP INDEX IF EXISTS "tracking_event";
+
+ALTER TABLE "tracking" ALTER COLUMN "amount" TYPE VARCHAR
+	USING trim_scale("amount" / power(10::NUMERIC, "decimals"))::VARCHAR;
+
+ALTER TABLE "tracking"
+	DROP COLUMN IF EXISTS "logIndex",
+	DROP COLUMN IF EXISTS "decimals",
+	DROP COLUMN IF EXISTS "blockNumber",
+	DROP COLUMN IF EXISTS "blockHash",
+	DROP COLUMN IF EXISTS "blockTime",
+	DROP COLUMN IF EXISTS "transactionIndex",
+	DROP COLUMN IF EXISTS "gasUsed",
+	DROP COLUMN IF EXISTS "effectiveGasPrice",
+	DROP COLUMN IF EXISTS "fee",
+	DROP COLUMN IF EXISTS "status",
+	DROP COLUMN IF EXISTS "fromLabel",
+	DROP COLUMN IF EXISTS "toLabel";
@@ -0,0 +1,82 @@
+-- Rows stored before log indexes were recorded: exact duplicates are dropped
+-- and the remaining transfers of a transaction are numbered -1, -2...
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'tracking' AND column_name = 'logIndex') THEN
+		ALTER TABLE "tracking" ADD COLUMN "logIndex" BIGINT;
+
+		DELETE FROM "tracking" a USING "tracking" b
+		WHERE a."id" > b."id"
+			AND a."chain" = b."chain"
+			AND a."transactionHash" = b."transactionHash"
+			AND a."type" = b."type"
+			AND a."from" = b."from"
+			AND a."to" = b."to"
+			AND a."amount" = b."amount"
+			AND a."token" IS NOT DISTINCT FROM b."token";
+
+		UPDATE "tracking" t SET "logIndex" = -n."position"
+		FROM (
+			SELECT "id", row_number() OVER (PARTITION BY "chain", "transactionHash" ORDER BY "id") AS "position"
+			FROM "tracking"
+		) n
+		WHERE t."id" = n."id";
+
+		ALTER TABLE "tracking" ALTER COLUMN "logIndex" SET NOT NULL;
+	END IF;
+END $$;
+
+ALTER TABLE "tracking"
+	ADD COLUMN IF NOT EXISTS "decimals" SMALLINT NOT NULL DEFAULT 18,
+	ADD COLUMN IF NOT EXISTS "blockNumber" BIGINT,
+	ADD COLUMN IF NOT EXISTS "blockHash" VARCHAR,
+	ADD COLUMN IF NOT EXISTS "blockTime" TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS "transactionIndex" BIGINT,
+	ADD COLUMN IF NOT EXISTS "gasUsed" BIGINT,
+	ADD COLUMN IF NOT EXISTS "effectiveGasPrice" NUMERIC(78,0),
+	ADD COLUMN IF NOT EXISTS "fee" NUMERIC(78,0),
+	ADD COLUMN IF NOT EXISTS "status" VARCHAR NOT NULL DEFAULT 'confirmed',
+	ADD COLUMN IF NOT EXISTS "fromLabel" VARCHAR,
+	ADD COLUMN IF NOT EXISTS "toLabel" VARCHAR;
+
+-- Amounts used to be stored as formatted decimal strings. They are converted
+-- to raw integers assuming the decimals of the row, 18 for legacy rows.
+DO $$
+BEGIN
+	IF (SELECT data_type FROM information_schema.columns
+		WHERE table_name = 'tracking' AND column_name = 'amount') <> 'numeric' THEN
+		ALTER TABLE "tracking" ALTER COLUMN "amount" TYPE NUMERIC(78,0)
+			USING round("amount"::NUMERIC * power(10::NUMERIC, "decimals"));
+	END IF;
+END $$;
+
+ALTER TABLE "tracking" ALTER COLUMN "decimals" DROP DEFAULT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "tracking_event"
+	ON "tracking" ("transactionHash", "logIndex", "chain");
+
+CREATE TABLE IF NOT EXISTS "checkpoints" (
+	"chain" VARCHAR NOT NULL,
+	"blockNumber" BIGINT NOT NULL,
+	"updatedAt" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+	PRIMARY KEY ("chain")
+);
+
+CREATE TABLE IF NOT EXISTS "blocks" (
+	"chain" VARCHAR NOT NULL,
+	"blockNumber" BIGINT NOT NULL,
+	"blockHash" VARCHAR NOT NULL,
+	"parentHash" VARCHAR NOT NULL,
+	PRIMARY KEY ("chain", "blockNumber")
+);
+
+CREATE TABLE IF NOT EXISTS "wallets" (
+	"id" BIGSERIAL NOT NULL,
+	"chain" VARCHAR NOT NULL,
+	"address" VARCHAR NOT NULL,
+	"label" VARCHAR,
+	"createdAt" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "wallets_chain_address" UNIQUE ("chain", "address")
+);
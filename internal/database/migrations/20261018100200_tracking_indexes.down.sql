@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS "tracking_block_time_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_to_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_from_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_type_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_symbol_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_transaction_hash_idx";
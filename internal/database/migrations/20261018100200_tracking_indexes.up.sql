@@ -0,0 +1,11 @@
+CREATE INDEX IF NOT EXISTS "tracking_transaction_hash_idx" ON "tracking" ("transactionHash");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_symbol_idx" ON "tracking" ("symbol");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_type_idx" ON "tracking" ("type");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_from_idx" ON "tracking" ("from");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_to_idx" ON "tracking" ("to");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_block_time_idx" ON "tracking" ("blockTime");
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "tracking_amount_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_token_idx";
+--bun:split
+DROP INDEX IF EXISTS "tracking_chain_block_number_idx";
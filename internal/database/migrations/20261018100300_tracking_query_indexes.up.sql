@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS "tracking_chain_block_number_idx" ON "tracking" ("chain", "blockNumber");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_token_idx" ON "tracking" ("token");
+--bun:split
+CREATE INDEX IF NOT EXISTS "tracking_amount_idx" ON "tracking" ("amount", "id");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "tracked_tokens";
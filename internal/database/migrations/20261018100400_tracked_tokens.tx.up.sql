@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "tracked_tokens" (
+	"id" BIGSERIAL NOT NULL,
+	"chain" VARCHAR NOT NULL,
+	"address" VARCHAR NOT NULL,
+	"label" VARCHAR,
+	"createdAt" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "tracked_tokens_chain_address" UNIQUE ("chain", "address")
+);
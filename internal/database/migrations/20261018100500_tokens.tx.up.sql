@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "tokens" (
+	"chain" VARCHAR NOT NULL,
+	"address" VARCHAR NOT NULL,
+	"name" VARCHAR,
+	"symbol" VARCHAR,
+	"decimals" SMALLINT NOT NULL,
+	"createdAt" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+	PRIMARY KEY ("chain", "address")
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "outbox" (
+	"id" BIGSERIAL NOT NULL,
+	"notifier" VARCHAR NOT NULL,
+	"subject" VARCHAR,
+	"text" TEXT NOT NULL,
+	"transfer" JSONB,
+	"status" VARCHAR NOT NULL,
+	"attempts" INTEGER NOT NULL DEFAULT 0,
+	"nextAttemptAt" TIMESTAMPTZ NOT NULL,
+	"lastError" TEXT,
+	"createdAt" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+	"deliveredAt" TIMESTAMPTZ,
+	PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "outbox_due_idx" ON "outbox" ("status", "nextAttemptAt");
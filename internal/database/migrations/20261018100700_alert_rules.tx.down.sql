@@ -0,0 +1,5 @@
+ALTER TABLE "outbox" DROP COLUMN IF EXISTS "severity";
+
+--bun:split
+
+DROP TABLE IF EXISTS "alert_rules";
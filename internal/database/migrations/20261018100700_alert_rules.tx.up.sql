@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS "alert_rules" (
+	"id" BIGSERIAL NOT NULL,
+	"name" VARCHAR,
+	"chain" VARCHAR,
+	"token" VARCHAR,
+	"direction" VARCHAR,
+	"counterparty" VARCHAR,
+	"minAmount" DOUBLE PRECISION,
+	"maxAmount" DOUBLE PRECISION,
+	"minUSD" DOUBLE PRECISION,
+	"maxUSD" DOUBLE PRECISION,
+	"notifiers" VARCHAR[],
+	"severity" VARCHAR NOT NULL,
+	"disabled" BOOLEAN NOT NULL DEFAULT FALSE,
+	"createdAt" TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS "severity" VARCHAR;
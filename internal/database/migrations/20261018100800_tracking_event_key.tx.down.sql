@@ -0,0 +1,2 @@
+-- The key belongs to 20261018100100, rolling back this repair keeps it.
+SELECT 1;
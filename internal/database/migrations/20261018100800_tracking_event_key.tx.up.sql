@@ -0,0 +1,30 @@
+-- Databases created before the schema was versioned may reach this point
+-- without the key that SaveTracking upserts on: 20261018100100 skips the
+-- legacy conversion when "logIndex" already exists, and only creates the
+-- index when no relation is named "tracking_event". The key is looked up by
+-- its columns, and created after dropping duplicate events when missing.
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		WHERE t.relname = 'tracking'
+			AND i.indisunique
+			AND i.indnatts = 3
+			AND (SELECT array_agg(a.attname::TEXT ORDER BY a.attname)
+				FROM pg_attribute a
+				WHERE a.attrelid = t.oid AND a.attnum = ANY(i.indkey))
+				= ARRAY['chain', 'logIndex', 'transactionHash']
+	) THEN
+		DELETE FROM "tracking" a USING "tracking" b
+		WHERE a."id" > b."id"
+			AND a."chain" = b."chain"
+			AND a."transactionHash" = b."transactionHash"
+			AND a."logIndex" = b."logIndex";
+
+		ALTER TABLE "tracking" DROP CONSTRAINT IF EXISTS "tracking_event";
+		DROP INDEX IF EXISTS "tracking_event";
+		CREATE UNIQUE INDEX "tracking_event"
+			ON "tracking" ("transactionHash", "logIndex", "chain");
+	END IF;
+END $$;
@@ -0,0 +1,5 @@
+ALTER TABLE "alert_rules" ALTER COLUMN "maxAmount" TYPE DOUBLE PRECISION USING "maxAmount"::double precision;
+
+--bun:split
+
+ALTER TABLE "alert_rules" ALTER COLUMN "minAmount" TYPE DOUBLE PRECISION USING "minAmount"::double precision;
@@ -0,0 +1,5 @@
+ALTER TABLE "alert_rules" ALTER COLUMN "minAmount" TYPE NUMERIC USING "minAmount"::numeric;
+
+--bun:split
+
+ALTER TABLE "alert_rules" ALTER COLUMN "maxAmount" TYPE NUMERIC USING "maxAmount"::numeric;
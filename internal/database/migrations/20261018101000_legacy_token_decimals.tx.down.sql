@@ -0,0 +1,3 @@
+-- The corrected decimals and amounts are kept: the values they replace were
+-- wrong.
+SELECT 1;
@@ -0,0 +1,27 @@
+-- 20261018100100 converted the amounts of legacy rows, which have no block
+-- number, to raw integers with 18 decimals. Legacy transfers of the tokens
+-- configured with 6 decimals at the time, USDT and USDC, were stored in whole
+-- tokens of 6 decimals, so their raw amounts came out 10^12 too large: they
+-- are scaled back. Other legacy tokens were divided by 10^18 when stored, so
+-- their raw amounts are right, and only their decimals are corrected from the
+-- resolved metadata of the token when it is known. Legacy rows of tokens that
+-- a custom config file gave other decimals cannot be told apart and keep 18.
+UPDATE "tracking" SET
+	"decimals" = 6,
+	"amount" = round("amount" / power(10::NUMERIC, 12))
+WHERE "blockNumber" IS NULL
+	AND "type" = 'Erc20Token'
+	AND "decimals" = 18
+	AND ("token" IN ('0xdac17f958d2ee523a2206206994597c13d831ec7', '0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48')
+		OR (COALESCE("token", '') = '' AND "symbol" IN ('USDT', 'USDC')));
+
+--bun:split
+
+UPDATE "tracking" t SET "decimals" = k."decimals"
+FROM "tokens" k
+WHERE t."blockNumber" IS NULL
+	AND t."type" = 'Erc20Token'
+	AND t."decimals" = 18
+	AND k."chain" = t."chain"
+	AND k."address" = t."token"
+	AND k."decimals" <> 18;
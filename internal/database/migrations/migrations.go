@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"embed"
+	"github.com/uptrace/bun/migrate"
+)
+
+//go:embed *.sql
+var sqlMigrations embed.FS
+
+// Migrations are the versioned schema migrations, applied in name order. A
+// migration is never edited once committed, as databases may have applied it:
+// schema changes and repairs go in a new migration.
+var Migrations = migrate.NewMigrations()
+
+func init() {
+	err := Migrations.Discover(sqlMigrations)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// BigInt is an exact on-chain integer such as a uint256 amount. It is stored
+// in a numeric column and encoded as a decimal string in JSON, so that no
+// precision is lost on either side. The zero value is 0.
+type BigInt struct {
+	*big.Int
+}
+
+func NewBigInt(i *big.Int) BigInt {
+	return BigInt{Int: new(big.Int).Set(i)}
+}
+
+// ParseBigInt parses a base 10 integer.
+func ParseBigInt(s string) (BigInt, error) {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return BigInt{}, fmt.Errorf("invalid integer %q", s)
+	}
+	return BigInt{Int: i}, nil
+}
+
+func (b BigInt) String() string {
+	if b.Int == nil {
+		return "0"
+	}
+	return b.Int.String()
+}
+
+// Compare returns -1, 0 or +1 depending on whether b is less than, equal to
+// or greater than y.
+func (b BigInt) Compare(y BigInt) int {
+	return b.orZero().Cmp(y.orZero())
+}
+
+func (b BigInt) orZero() *big.Int {
+	if b.Int == nil {
+		return new(big.Int)
+	}
+	return b.Int
+}
+
+// Format renders the integer as a decimal number with the given number of
+// decimals, without trailing zeros, e.g. 1500000 with 6 decimals is "1.5".
+func (b BigInt) Format(decimals uint8) string {
+	digits := b.String()
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(decimals)
+	formatted := digits[:point]
+	if fraction := strings.TrimRight(digits[point:], "0"); fraction != "" {
+		formatted += "." + fraction
+	}
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+func (b BigInt) Value() (driver.Value, error) {
+	return b.String(), nil
+}
+
+func (b *BigInt) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		b.Int = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		b.Int = big.NewInt(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into BigInt", src)
+	}
+	parsed, err := ParseBigInt(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+func (b BigInt) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + b.String() + `"`), nil
+}
+
+// UnmarshalJSON reads a number or a decimal string; null is zero.
+func (b *BigInt) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*b = BigInt{}
+		return nil
+	}
+	parsed, err := ParseBigInt(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Decimal is an exact decimal number such as an amount in whole tokens. Like
+// BigInt, it is stored in a numeric column and encoded as a decimal string in
+// JSON. The zero value is 0.
+type Decimal struct {
+	*big.Rat
+}
+
+// ParseDecimal parses a base 10 decimal number, e.g. "1.5".
+func ParseDecimal(s string) (Decimal, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/xXbBoO_") {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal{Rat: r}, nil
+}
+
+// Whole returns the integer divided by 10^decimals, e.g. a raw amount of a
+// token in whole tokens.
+func (b BigInt) Whole(decimals uint8) Decimal {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return Decimal{Rat: new(big.Rat).SetFrac(b.orZero(), unit)}
+}
+
+// String renders the number with as many decimals as it has.
+func (d Decimal) String() string {
+	if d.Rat == nil {
+		return "0"
+	}
+	// the denominator of a decimal number only has the factors 2 and 5
+	denominator := new(big.Int).Set(d.Rat.Denom())
+	decimals := 0
+	for _, factor := range []int64{2, 5} {
+		count := 0
+		divisor := big.NewInt(factor)
+		remainder := new(big.Int)
+		for {
+			quotient, rem := new(big.Int).QuoRem(denominator, divisor, remainder)
+			if rem.Sign() != 0 {
+				break
+			}
+			denominator = quotient
+			count++
+		}
+		decimals = max(decimals, count)
+	}
+	return d.Rat.FloatString(decimals)
+}
+
+// Compare returns -1, 0 or +1 depending on whether d is less than, equal to
+// or greater than y.
+func (d Decimal) Compare(y Decimal) int {
+	return d.orZero().Cmp(y.orZero())
+}
+
+func (d Decimal) orZero() *big.Rat {
+	if d.Rat == nil {
+		return new(big.Rat)
+	}
+	return d.Rat
+}
+
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Decimal) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		d.Rat = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		d.Rat = new(big.Rat).SetInt64(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Decimal", src)
+	}
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON reads a number or a decimal string; null is zero.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = Decimal{}
+		return nil
+	}
+	parsed, err := ParseDecimal(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
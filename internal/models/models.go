@@ -1,30 +1,263 @@
 package models
 
-import "github.com/uptrace/bun"
+import (
+	"github.com/uptrace/bun"
+	"time"
+)
 
 type TokenConfig struct {
 	TokenName string `json:"TokenName"`
 	Symbol    string `json:"Symbol"`
 	Decimals  uint8  `json:"Decimals"`
+	// PriceUSD is the price of one token shown in alerts, none if 0.
+	PriceUSD float64 `json:"PriceUSD,omitempty"`
+}
+
+type WalletConfig struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	// Notify names the notifiers alerted of the transfers of the wallet, in
+	// addition to the ones of its chain.
+	Notify []string `json:"notify,omitempty"`
+}
+
+type RPCEndpoint struct {
+	URL string `json:"url"`
+	// RPS is the requests per second budget of the endpoint, unlimited if 0.
+	RPS float64 `json:"rps,omitempty"`
+}
+
+// Config is the content of config.json.
+type Config struct {
+	Chains []ChainConfig `json:"chains"`
+	// Notifiers are the alert destinations, by name. Without any, alerts are
+	// sent to the Telegram chat of the TELEGRAM_BOT_TOKEN and
+	// TELEGRAM_CHAT_ID environment variables.
+	Notifiers map[string]NotifierConfig `json:"notifiers,omitempty"`
+}
+
+// NotifierConfig configures an alert destination. Which fields apply depends
+// on Type: telegram, slack, discord, webhook or email. Environment variables
+// such as ${SLACK_WEBHOOK} are expanded in every field.
+type NotifierConfig struct {
+	Type string `json:"type"`
+	// URL is the webhook of slack, discord and webhook notifiers.
+	URL string `json:"url,omitempty"`
+	// Secret signs the body of webhook notifiers with HMAC-SHA256.
+	Secret string `json:"secret,omitempty"`
+	// Token and ChatID are the bot and the chat of telegram notifiers, and
+	// Endpoint the Bot API endpoint, api.telegram.org if empty.
+	Token    string `json:"token,omitempty"`
+	ChatID   string `json:"chatId,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Host is the SMTP server of email notifiers as host:port, and Username
+	// and Password its credentials if it requires authentication.
+	Host     string   `json:"host,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	// Format is how telegram notifiers send the alert text: text, markdownv2
+	// or html.
+	Format string `json:"format,omitempty"`
+	// Commands makes telegram notifiers answer bot commands managing the
+	// tracker, sent from their chat or one of CommandChats.
+	Commands     bool     `json:"commands,omitempty"`
+	CommandChats []string `json:"commandChats,omitempty"`
+	// Templates replace the text/template of the alerts, by event type:
+	// transfer or correction. See notify.Event for what they are executed
+	// with.
+	Templates map[string]string `json:"templates,omitempty"`
 }
 
 type ChainConfig struct {
-	Chain                string                 `json:"chain"`
-	ChainSymbol          string                 `json:"chainSymbol"`
-	UsersTracking        string                 `json:"usersTracking"`
+	Chain       string `json:"chain"`
+	ChainSymbol string `json:"chainSymbol"`
+	// RPC is the endpoint URL of the chain. Environment variables such as
+	// ${RPC} are expanded.
+	RPC string `json:"rpc,omitempty"`
+	// RPCEndpoints are more endpoints used for failover along with RPC.
+	RPCEndpoints []RPCEndpoint `json:"rpcEndpoints,omitempty"`
+	// WS is an optional WebSocket endpoint used to subscribe to new heads.
+	// Without it, or while the subscription is down, the head is polled.
+	WS string `json:"ws,omitempty"`
+	// WSRPS is the budget of WS in connections per second, unlimited if 0.
+	WSRPS float64 `json:"wsRps,omitempty"`
+	// Explorer is the block explorer linked from alerts, such as
+	// https://etherscan.io.
+	Explorer string `json:"explorer,omitempty"`
+	// PriceUSD is the price of the native token shown in alerts, none if 0.
+	PriceUSD float64 `json:"priceUSD,omitempty"`
+	// Notify names the notifiers alerted of the transfers of the chain. When
+	// neither the chain nor the wallets of a transfer name any, every notifier
+	// is alerted.
+	Notify               []string               `json:"notify,omitempty"`
+	Wallets              []WalletConfig         `json:"wallets"`
 	TrackingTokensConfig map[string]TokenConfig `json:"trackingTokensConfig"`
 	ListTokensTracking   []string               `json:"listTokensTracking"`
+	// StartBlock is the first block to scan when there is no checkpoint for
+	// the chain yet, or when the stored checkpoint is behind it.
+	StartBlock uint64 `json:"startBlock,omitempty"`
+	// Confirmations is how many blocks the tracker stays behind the head.
+	Confirmations uint64 `json:"confirmations,omitempty"`
+	// PendingConfirmations makes the tracker follow the head and store new
+	// transfers as pending; they are confirmed and announced, or invalidated,
+	// once Confirmations blocks deep.
+	PendingConfirmations bool `json:"pendingConfirmations,omitempty"`
+}
+
+// Endpoints returns the RPC endpoints of the chain, RPC first.
+func (c ChainConfig) Endpoints() []RPCEndpoint {
+	var endpoints []RPCEndpoint
+	if c.RPC != "" {
+		endpoints = append(endpoints, RPCEndpoint{URL: c.RPC})
+	}
+	return append(endpoints, c.RPCEndpoints...)
 }
 
+// NativeLogIndex is the log index of native token transfers, which are not
+// logged by any contract.
+const NativeLogIndex = -1
+
+const (
+	StatusConfirmed   = "confirmed"
+	StatusPending     = "pending"
+	StatusInvalidated = "invalidated"
+)
+
+// TrackingInformation is a tracked transfer. Amount is the raw on-chain
+// amount in the smallest unit of the token, and Fee the transaction fee paid
+// by the sender, in wei.
 type TrackingInformation struct {
-	bun.BaseModel   `bun:"table:tracking"`
-	ID              int    `bun:",pk,autoincrement"`
-	TransactionHash string `bun:"transactionHash,notnull" json:"transactionHash"`
-	Type            string `bun:"type,notnull" json:"type"`
-	From            string `bun:"from,notnull" json:"from"`
-	To              string `bun:"to,notnull" json:"to"`
-	Chain           string `bun:"chain,notnull" json:"chain"`
-	Token           string `bun:"token" json:"token"`
-	Symbol          string `bun:"symbol" json:"symbol"`
-	Amount          string `bun:"amount,notnull" json:"amount"`
+	bun.BaseModel     `bun:"table:tracking"`
+	ID                int       `bun:",pk,autoincrement"`
+	TransactionHash   string    `bun:"transactionHash,notnull,unique:tracking_event" json:"transactionHash"`
+	LogIndex          int       `bun:"logIndex,notnull,unique:tracking_event" json:"logIndex"`
+	Type              string    `bun:"type,notnull" json:"type"`
+	From              string    `bun:"from,notnull" json:"from"`
+	To                string    `bun:"to,notnull" json:"to"`
+	Chain             string    `bun:"chain,notnull,unique:tracking_event" json:"chain"`
+	Token             string    `bun:"token" json:"token"`
+	Symbol            string    `bun:"symbol" json:"symbol"`
+	Amount            BigInt    `bun:"amount,type:numeric(78,0),notnull" json:"amount"`
+	Decimals          uint8     `bun:"decimals,notnull" json:"decimals"`
+	BlockNumber       uint64    `bun:"blockNumber" json:"blockNumber"`
+	BlockHash         string    `bun:"blockHash" json:"blockHash"`
+	BlockTime         time.Time `bun:"blockTime" json:"blockTime"`
+	TransactionIndex  uint      `bun:"transactionIndex" json:"transactionIndex"`
+	GasUsed           uint64    `bun:"gasUsed" json:"gasUsed"`
+	EffectiveGasPrice BigInt    `bun:"effectiveGasPrice,type:numeric(78,0)" json:"effectiveGasPrice"`
+	Fee               BigInt    `bun:"fee,type:numeric(78,0)" json:"fee"`
+	Status            string    `bun:"status,notnull,default:'confirmed'" json:"status"`
+	FromLabel         string    `bun:"fromLabel" json:"fromLabel,omitempty"`
+	ToLabel           string    `bun:"toLabel" json:"toLabel,omitempty"`
+}
+
+// Wallet is a watched address of a chain, in addition to the wallets of the
+// config file.
+type Wallet struct {
+	bun.BaseModel `bun:"table:wallets"`
+	ID            int       `bun:",pk,autoincrement" json:"id"`
+	Chain         string    `bun:"chain,notnull,unique:wallets_chain_address" json:"chain"`
+	Address       string    `bun:"address,notnull,unique:wallets_chain_address" json:"address"`
+	Label         string    `bun:"label" json:"label"`
+	CreatedAt     time.Time `bun:"createdAt,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// TrackedToken is a tracked token contract of a chain, in addition to the
+// tokens of the config file.
+type TrackedToken struct {
+	bun.BaseModel `bun:"table:tracked_tokens"`
+	ID            int       `bun:",pk,autoincrement" json:"id"`
+	Chain         string    `bun:"chain,notnull,unique:tracked_tokens_chain_address" json:"chain"`
+	Address       string    `bun:"address,notnull,unique:tracked_tokens_chain_address" json:"address"`
+	Label         string    `bun:"label" json:"label"`
+	CreatedAt     time.Time `bun:"createdAt,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// Token is the metadata of a token contract, read from the chain the first
+// time a transfer of the token is tracked.
+type Token struct {
+	bun.BaseModel `bun:"table:tokens"`
+	Chain         string    `bun:"chain,pk" json:"chain"`
+	Address       string    `bun:"address,pk" json:"address"`
+	Name          string    `bun:"name" json:"name"`
+	Symbol        string    `bun:"symbol" json:"symbol"`
+	Decimals      uint8     `bun:"decimals,notnull" json:"decimals"`
+	CreatedAt     time.Time `bun:"createdAt,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// OutboxMessage is an alert waiting to be delivered, or that was, by a
+// notifier. It is written along with the change it announces, so that an
+// alert is not lost when its notifier is down. Transfer is the transfer
+// announced, as it was then.
+type OutboxMessage struct {
+	bun.BaseModel `bun:"table:outbox"`
+	ID            int64                `bun:",pk,autoincrement" json:"id"`
+	Notifier      string               `bun:"notifier,notnull" json:"notifier"`
+	Subject       string               `bun:"subject" json:"subject"`
+	Text          string               `bun:"text,notnull" json:"text"`
+	Transfer      *TrackingInformation `bun:"transfer,type:jsonb" json:"transfer,omitempty"`
+	Severity      string               `bun:"severity" json:"severity,omitempty"`
+	Status        string               `bun:"status,notnull" json:"status"`
+	Attempts      int                  `bun:"attempts,notnull" json:"attempts"`
+	NextAttemptAt time.Time            `bun:"nextAttemptAt,notnull" json:"nextAttemptAt"`
+	LastError     string               `bun:"lastError" json:"lastError,omitempty"`
+	CreatedAt     time.Time            `bun:"createdAt,notnull,default:current_timestamp" json:"createdAt"`
+	DeliveredAt   time.Time            `bun:"deliveredAt,nullzero" json:"deliveredAt,omitempty"`
+}
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// AlertRule decides which transfers are alerted, to which notifiers and with
+// which severity. A transfer matches a rule when it matches every condition
+// set; unset conditions match anything. Token is a token address, or native
+// for the native token. MinAmount and MaxAmount are exact amounts in whole
+// tokens, compared with the decimals of each transfer, and MinUSD and MaxUSD
+// need the price of the token to be configured. Notifiers are the notifiers
+// alerted, those of the chain and wallets of the transfer if empty.
+type AlertRule struct {
+	bun.BaseModel `bun:"table:alert_rules"`
+	ID            int64     `bun:",pk,autoincrement" json:"id"`
+	Name          string    `bun:"name" json:"name"`
+	Chain         string    `bun:"chain" json:"chain,omitempty"`
+	Token         string    `bun:"token" json:"token,omitempty"`
+	Direction     string    `bun:"direction" json:"direction,omitempty"`
+	Counterparty  string    `bun:"counterparty" json:"counterparty,omitempty"`
+	MinAmount     *Decimal  `bun:"minAmount,type:numeric" json:"minAmount,omitempty"`
+	MaxAmount     *Decimal  `bun:"maxAmount,type:numeric" json:"maxAmount,omitempty"`
+	MinUSD        *float64  `bun:"minUSD" json:"minUSD,omitempty"`
+	MaxUSD        *float64  `bun:"maxUSD" json:"maxUSD,omitempty"`
+	Notifiers     []string  `bun:"notifiers,array" json:"notifiers,omitempty"`
+	Severity      string    `bun:"severity,notnull" json:"severity"`
+	Disabled      bool      `bun:"disabled,notnull" json:"disabled"`
+	CreatedAt     time.Time `bun:"createdAt,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// Checkpoint is the last fully processed block of a chain.
+type Checkpoint struct {
+	bun.BaseModel `bun:"table:checkpoints"`
+	Chain         string    `bun:"chain,pk" json:"chain"`
+	BlockNumber   uint64    `bun:"blockNumber,notnull" json:"blockNumber"`
+	UpdatedAt     time.Time `bun:"updatedAt,notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+// TrackedBlock is the hash of a processed block, kept for a while to detect
+// chain reorganizations.
+type TrackedBlock struct {
+	bun.BaseModel `bun:"table:blocks,alias:b"`
+	Chain         string `bun:"chain,pk" json:"chain"`
+	BlockNumber   uint64 `bun:"blockNumber,pk" json:"blockNumber"`
+	BlockHash     string `bun:"blockHash,notnull" json:"blockHash"`
+	ParentHash    string `bun:"parentHash,notnull" json:"parentHash"`
 }
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// discordMaxLength is the longest message content Discord accepts.
+const discordMaxLength = 2000
+
+// Discord posts alerts to a Discord webhook.
+type Discord struct {
+	url string
+}
+
+func NewDiscord(url string) (*Discord, error) {
+	if url == "" {
+		return nil, fmt.Errorf("discord webhook URL is required")
+	}
+	return &Discord{url: url}, nil
+}
+
+func (n *Discord) Notify(ctx context.Context, message Message) error {
+	content := []rune(message.Text)
+	if len(content) > discordMaxLength {
+		content = append(content[:discordMaxLength-1], '…')
+	}
+	return postJSON(ctx, n.url, map[string]string{"content": string(content)}, nil)
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends alerts by mail through an SMTP server.
+type Email struct {
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewEmail returns a notifier mailing to through the SMTP server at host, a
+// host:port address. Without username, the server is used without
+// authentication.
+func NewEmail(host string, username string, password string, from string, to []string) (*Email, error) {
+	_, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP host %q: %v", host, err)
+	}
+	if from == "" || len(to) == 0 {
+		return nil, fmt.Errorf("email sender and recipients are required")
+	}
+	return &Email{host: host, username: username, password: password, from: from, to: to}, nil
+}
+
+func (n *Email) Notify(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if n.username != "" {
+		hostname, _, _ := net.SplitHostPort(n.host)
+		auth = smtp.PlainAuth("", n.username, n.password, hostname)
+	}
+	subject := message.Subject
+	if subject == "" {
+		subject = "Transfer alert"
+	}
+
+	var mail bytes.Buffer
+	fmt.Fprintf(&mail, "From: %s\r\n", n.from)
+	fmt.Fprintf(&mail, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&mail, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&mail, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	mail.WriteString("\r\n")
+	mail.WriteString(strings.ReplaceAll(message.Text, "\n", "\r\n"))
+	mail.WriteString("\r\n")
+
+	// smtp.SendMail cannot be cancelled, so it runs apart from ctx
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(n.host, auth, n.from, n.to, mail.Bytes())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
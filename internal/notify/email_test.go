@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is an SMTP server accepting a single mail, or rejecting it with
+// rejectCode.
+type fakeSMTP struct {
+	listener   net.Listener
+	rejectCode int
+
+	auth string
+	from string
+	to   []string
+	data chan string
+}
+
+func newFakeSMTP(t *testing.T, rejectCode int) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTP{listener: listener, rejectCode: rejectCode, data: make(chan string, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) {
+		text.PrintfLine(format, args...)
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch command {
+		case "EHLO":
+			reply("250-fake")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+			reply("235 authenticated")
+		case "MAIL":
+			s.from = line
+			if s.rejectCode != 0 {
+				reply("%d rejected", s.rejectCode)
+				continue
+			}
+			reply("250 OK")
+		case "RCPT":
+			s.to = append(s.to, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.data <- strings.Join(lines, "\n")
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailSends(t *testing.T) {
+	server := newFakeSMTP(t, 0)
+	email, err := NewEmail(server.listener.Addr().String(), "user", "password", "alerts@example.com", []string{"a@example.com", "b@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = email.Notify(context.Background(), Message{Subject: "Transfer on eth ✓", Text: "line 1\nline 2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data string
+	select {
+	case data = <-server.data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+	headers, body, _ := strings.Cut(data, "\n\n")
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(headers + "\n\n")))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("failed to read headers of %q: %v", data, err)
+	}
+	if header.Get("From") != "alerts@example.com" || header.Get("To") != "a@example.com, b@example.com" {
+		t.Errorf("From %q To %q, want the sender and both recipients", header.Get("From"), header.Get("To"))
+	}
+	if header.Get("Subject") != "=?utf-8?q?Transfer_on_eth_=E2=9C=93?=" {
+		t.Errorf("Subject = %q, want it Q-encoded", header.Get("Subject"))
+	}
+	if body != "line 1\nline 2" {
+		t.Errorf("body = %q, want the text", body)
+	}
+	if len(server.to) != 2 {
+		t.Errorf("recipients = %v, want 2", server.to)
+	}
+	credentials, _ := base64.StdEncoding.DecodeString(server.auth)
+	if string(credentials) != "\x00user\x00password" {
+		t.Errorf("credentials = %q, want user and password", credentials)
+	}
+}
+
+func TestEmailFailsWhenRejected(t *testing.T) {
+	server := newFakeSMTP(t, 550)
+	email, _ := NewEmail(server.listener.Addr().String(), "", "", "alerts@example.com", []string{"a@example.com"})
+	err := email.Notify(context.Background(), Message{Text: "a transfer"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("error = %v, want the rejection", err)
+	}
+}
+
+func TestEmailStopsWithContext(t *testing.T) {
+	// a server that accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	email, _ := NewEmail(listener.Addr().String(), "", "", "alerts@example.com", []string{"a@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = email.Notify(ctx, Message{Text: "a transfer"})
+	if err != context.DeadlineExceeded {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNewEmailChecksConfig(t *testing.T) {
+	for _, test := range []struct {
+		host string
+		from string
+		to   []string
+	}{
+		{host: "smtp.example.com", from: "a@example.com", to: []string{"b@example.com"}},
+		{host: "smtp.example.com:25", to: []string{"b@example.com"}},
+		{host: "smtp.example.com:25", from: "a@example.com"},
+	} {
+		_, err := NewEmail(test.host, "", "", test.from, test.to)
+		if err == nil {
+			t.Errorf("NewEmail(%q, %q, %v) succeeded, want an error", test.host, test.from, test.to)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpTimeout bounds a single webhook delivery.
+const httpTimeout = 10 * time.Second
+
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// postJSON posts body as JSON to url, failing unless the answer is a 2xx.
+func postJSON(ctx context.Context, url string, body interface{}, header http.Header) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return post(ctx, url, payload, header)
+}
+
+func post(ctx context.Context, url string, payload []byte, header http.Header) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("webhook answered %s: %s", response.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package notify
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Notifier delivers alerts to a destination.
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// Message is an alert. Transfer is the transfer the alert is about, if any,
+// which notifiers taking structured data send along with the text.
+type Message struct {
+	Subject  string                      `json:"subject"`
+	Text     string                      `json:"text"`
+	Severity string                      `json:"severity,omitempty"`
+	Transfer *models.TrackingInformation `json:"transfer,omitempty"`
+}
+
+// defaultNotifier is used when the config file names no notifier, as alerts
+// were sent to this Telegram chat before notifiers were configurable.
+var defaultNotifier = models.NotifierConfig{
+	Type:   "telegram",
+	Token:  "${TELEGRAM_BOT_TOKEN}",
+	ChatID: "${TELEGRAM_CHAT_ID}",
+}
+
+// New returns the notifier of config, after expanding the environment
+// variables of its fields.
+func New(config models.NotifierConfig) (Notifier, error) {
+	config = expandEnv(config)
+	if config.Type != "telegram" && config.Format != "" && config.Format != FormatText {
+		return nil, fmt.Errorf("format %s is only supported by telegram notifiers", config.Format)
+	}
+	if config.Type != "telegram" && (config.Commands || len(config.CommandChats) > 0) {
+		return nil, fmt.Errorf("commands are only supported by telegram notifiers")
+	}
+	for _, chat := range config.CommandChats {
+		_, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse command chat ID: %v", err)
+		}
+	}
+	switch config.Type {
+	case "telegram":
+		return NewTelegram(config.Token, config.ChatID, config.Endpoint, config.Format)
+	case "slack":
+		return NewSlack(config.URL)
+	case "discord":
+		return NewDiscord(config.URL)
+	case "webhook":
+		return NewWebhook(config.URL, config.Secret)
+	case "email":
+		return NewEmail(config.Host, config.Username, config.Password, config.From, config.To)
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", config.Type)
+}
+
+func expandEnv(config models.NotifierConfig) models.NotifierConfig {
+	for _, field := range []*string{
+		&config.URL, &config.Secret, &config.Token, &config.ChatID, &config.Endpoint,
+		&config.Host, &config.Username, &config.Password, &config.From,
+	} {
+		*field = os.ExpandEnv(*field)
+	}
+	config.To = expandEnvAll(config.To)
+	config.CommandChats = expandEnvAll(config.CommandChats)
+	return config
+}
+
+func expandEnvAll(values []string) []string {
+	expanded := make([]string, len(values))
+	for i, value := range values {
+		expanded[i] = os.ExpandEnv(value)
+	}
+	return expanded
+}
+
+// Registry holds the configured notifiers by name. It is safe for concurrent
+// use, and can be reloaded when the config changes.
+type Registry struct {
+	mu        sync.RWMutex
+	notifiers map[string]Notifier
+	templates map[string]*Templates
+	// configs are the configs of the notifiers, environment variables
+	// expanded.
+	configs map[string]models.NotifierConfig
+	names   []string
+	// changed is closed and replaced on each reload, see Changed.
+	changed chan struct{}
+	// muted maps notifiers to the end of their mute, see Mute.
+	muted map[string]time.Time
+}
+
+// NewRegistry returns the notifiers of configs, or the default Telegram
+// notifier if configs is empty. Alerts are disabled if the environment
+// variables of the default notifier are not set.
+func NewRegistry(configs map[string]models.NotifierConfig) (*Registry, error) {
+	registry := new(Registry)
+	err := registry.Reload(configs)
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Reload replaces the notifiers with those of configs, see NewRegistry. The
+// notifiers are kept if one of configs is invalid.
+func (r *Registry) Reload(configs map[string]models.NotifierConfig) error {
+	notifiers := make(map[string]Notifier)
+	templates := make(map[string]*Templates)
+	expanded := make(map[string]models.NotifierConfig)
+	var names []string
+	if len(configs) == 0 {
+		notifier, err := New(defaultNotifier)
+		if err != nil {
+			fmt.Printf("Telegram alerts disabled: %v\n", err)
+		} else {
+			configs = map[string]models.NotifierConfig{"telegram": defaultNotifier}
+			notifiers["telegram"] = notifier
+		}
+	}
+	for name, config := range configs {
+		notifier, ok := notifiers[name]
+		if !ok {
+			var err error
+			notifier, err = New(config)
+			if err != nil {
+				return fmt.Errorf("notifier %s: %w", name, err)
+			}
+		}
+		nameTemplates, err := NewTemplates(config.Format, config.Templates)
+		if err != nil {
+			return fmt.Errorf("notifier %s: %w", name, err)
+		}
+		notifiers[name] = notifier
+		templates[name] = nameTemplates
+		expanded[name] = expandEnv(config)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifiers = notifiers
+	r.templates = templates
+	r.configs = expanded
+	r.names = names
+	if r.changed != nil {
+		close(r.changed)
+	}
+	r.changed = make(chan struct{})
+	return nil
+}
+
+// Changed returns a channel closed when the notifiers are next reloaded.
+func (r *Registry) Changed() <-chan struct{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.changed
+}
+
+// Names returns the names of the notifiers, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.names
+}
+
+// Config returns the config of the notifier named name, with its environment
+// variables expanded.
+func (r *Registry) Config(name string) (models.NotifierConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	config, ok := r.configs[name]
+	return config, ok
+}
+
+// Render returns the alert of event for the notifier named name, see
+// Templates.Render.
+func (r *Registry) Render(name string, event Event) (Message, error) {
+	r.mu.RLock()
+	templates, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Message{}, fmt.Errorf("unknown notifier %s", name)
+	}
+	return templates.Render(event)
+}
+
+// Get returns the notifier named name.
+func (r *Registry) Get(name string) (Notifier, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notifier, ok := r.notifiers[name]
+	return notifier, ok
+}
+
+// Mute stops alerting the notifier named name until until. A zero until
+// unmutes it. Mutes are kept when the registry is reloaded.
+func (r *Registry) Mute(name string, until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if until.IsZero() {
+		delete(r.muted, name)
+		return
+	}
+	if r.muted == nil {
+		r.muted = make(map[string]time.Time)
+	}
+	r.muted[name] = until
+}
+
+// Select returns the distinct names among names that are configured and not
+// muted, or every such name if names is empty.
+func (r *Registry) Select(names []string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(names) == 0 {
+		names = r.names
+	}
+	now := time.Now()
+	var selected []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := r.notifiers[name]; !ok || seen[name] || now.Before(r.muted[name]) {
+			continue
+		}
+		seen[name] = true
+		selected = append(selected, name)
+	}
+	return selected
+}
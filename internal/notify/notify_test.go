@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// capture serves status and records the last request body and headers.
+type capture struct {
+	status int
+	body   []byte
+	header http.Header
+}
+
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, *capture) {
+	captured := &capture{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		captured.body, _ = io.ReadAll(r.Body)
+		captured.header = r.Header.Clone()
+		w.WriteHeader(captured.status)
+		io.WriteString(w, "detail of the answer")
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+func TestSlackPayload(t *testing.T) {
+	server, captured := newCaptureServer(t, http.StatusOK)
+	slack, err := NewSlack(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = slack.Notify(context.Background(), Message{Subject: "subject", Text: "a *transfer*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]string
+	err = json.Unmarshal(captured.body, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payload) != 1 || payload["text"] != "a *transfer*" {
+		t.Errorf("payload = %v, want the text alone", payload)
+	}
+	if contentType := captured.header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Content-Type = %s, want application/json", contentType)
+	}
+}
+
+func TestDiscordPayload(t *testing.T) {
+	server, captured := newCaptureServer(t, http.StatusNoContent)
+	discord, err := NewDiscord(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "a transfer", want: "a transfer"},
+		{text: strings.Repeat("é", discordMaxLength), want: strings.Repeat("é", discordMaxLength)},
+		{text: strings.Repeat("é", discordMaxLength+1), want: strings.Repeat("é", discordMaxLength-1) + "…"},
+	}
+	for _, test := range tests {
+		err = discord.Notify(context.Background(), Message{Text: test.text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var payload map[string]string
+		err = json.Unmarshal(captured.body, &payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload["content"] != test.want {
+			t.Errorf("content of %d runes has %d runes, want %d", len([]rune(test.text)), len([]rune(payload["content"])), len([]rune(test.want)))
+		}
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	server, captured := newCaptureServer(t, http.StatusAccepted)
+	message := Message{
+		Subject:  "Transfer on eth",
+		Text:     "a transfer",
+		Severity: models.SeverityWarning,
+		Transfer: &models.TrackingInformation{Chain: "eth", TransactionHash: "0xabc"},
+	}
+
+	webhook, err := NewWebhook(server.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = webhook.Notify(context.Background(), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the receiver authenticates the body it got with the shared secret
+	if signature := captured.header.Get(SignatureHeader); signature != Sign("secret", captured.body) {
+		t.Errorf("%s = %s, want %s", SignatureHeader, signature, Sign("secret", captured.body))
+	}
+	if signature := captured.header.Get(SignatureHeader); signature == Sign("other", captured.body) {
+		t.Errorf("%s does not depend on the secret", SignatureHeader)
+	}
+	var received Message
+	err = json.Unmarshal(captured.body, &received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.Subject != message.Subject || received.Severity != message.Severity || received.Transfer == nil || received.Transfer.TransactionHash != "0xabc" {
+		t.Errorf("body = %+v, want %+v", received, message)
+	}
+
+	unsigned, _ := NewWebhook(server.URL, "")
+	err = unsigned.Notify(context.Background(), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature := captured.header.Get(SignatureHeader); signature != "" {
+		t.Errorf("%s = %s without a secret, want none", SignatureHeader, signature)
+	}
+}
+
+func TestHTTPNotifiersFailOnErrorStatus(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		server, _ := newCaptureServer(t, status)
+		slack, _ := NewSlack(server.URL)
+		discord, _ := NewDiscord(server.URL)
+		webhook, _ := NewWebhook(server.URL, "secret")
+		for name, notifier := range map[string]Notifier{"slack": slack, "discord": discord, "webhook": webhook} {
+			err := notifier.Notify(context.Background(), Message{Text: "a transfer"})
+			if err == nil {
+				t.Errorf("%s answered with %d: no error, want one", name, status)
+				continue
+			}
+			if !strings.Contains(err.Error(), "detail of the answer") {
+				t.Errorf("%s error %q does not carry the answer", name, err)
+			}
+		}
+	}
+}
+
+func TestHTTPNotifiersFailWhenUnreachable(t *testing.T) {
+	server, _ := newCaptureServer(t, http.StatusOK)
+	url := server.URL
+	server.Close()
+	webhook, _ := NewWebhook(url, "")
+	err := webhook.Notify(context.Background(), Message{Text: "a transfer"})
+	if err == nil {
+		t.Error("no error from a closed server, want one")
+	}
+}
@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// Slack posts alerts to a Slack incoming webhook.
+type Slack struct {
+	url string
+}
+
+func NewSlack(url string) (*Slack, error) {
+	if url == "" {
+		return nil, fmt.Errorf("slack webhook URL is required")
+	}
+	return &Slack{url: url}, nil
+}
+
+func (n *Slack) Notify(ctx context.Context, message Message) error {
+	return postJSON(ctx, n.url, map[string]string{"text": message.Text}, nil)
+}
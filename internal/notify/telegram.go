@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Telegram sends alerts to a Telegram chat. The bot client is created on the
+// first alert and reused.
+type Telegram struct {
+	token     string
+	chatID    int64
+	endpoint  string
+	parseMode string
+
+	mu  sync.Mutex
+	bot *tgbotapi.BotAPI
+}
+
+// NewTelegram returns a notifier of the chat chatID. endpoint is the Bot API
+// endpoint format, see tgbotapi.APIEndpoint, which is used if it is empty.
+// format is the format of the alert text, see FormatText.
+func NewTelegram(token string, chatID string, endpoint string, format string) (*Telegram, error) {
+	if token == "" {
+		return nil, fmt.Errorf("telegram token is required")
+	}
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse telegram chat ID: %v", err)
+	}
+	if endpoint == "" {
+		endpoint = tgbotapi.APIEndpoint
+	}
+	parseMode := ""
+	switch format {
+	case "", FormatText:
+	case FormatMarkdownV2:
+		parseMode = tgbotapi.ModeMarkdownV2
+	case FormatHTML:
+		parseMode = tgbotapi.ModeHTML
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return &Telegram{token: token, chatID: id, endpoint: endpoint, parseMode: parseMode}, nil
+}
+
+// ChatID returns the chat the alerts are sent to.
+func (n *Telegram) ChatID() int64 {
+	return n.chatID
+}
+
+// SameBot reports whether other sends with the same bot as n.
+func (n *Telegram) SameBot(other *Telegram) bool {
+	return n.token == other.token && n.endpoint == other.endpoint
+}
+
+// Bot returns the bot client, creating it if needed.
+func (n *Telegram) Bot() (*tgbotapi.BotAPI, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.bot == nil {
+		bot, err := tgbotapi.NewBotAPIWithClient(n.token, n.endpoint, &http.Client{Timeout: httpTimeout})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create telegram bot: %w", err)
+		}
+		n.bot = bot
+	}
+	return n.bot, nil
+}
+
+func (n *Telegram) Notify(ctx context.Context, message Message) error {
+	bot, err := n.Bot()
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(n.chatID, message.Text)
+	msg.ParseMode = n.parseMode
+	msg.DisableWebPagePreview = true
+	_, err = bot.Send(msg)
+	if err != nil {
+		return fmt.Errorf("failed to send telegram message: %w", err)
+	}
+	return nil
+}
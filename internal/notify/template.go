@@ -0,0 +1,269 @@
+package notify
+
+import (
+	"Intermediate_web3/internal/models"
+	"bytes"
+	"fmt"
+	"html"
+	"math/big"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Event types, which alerts have a template for.
+const (
+	EventTransfer   = "transfer"
+	EventCorrection = "correction"
+)
+
+// Formats of the alert text.
+const (
+	FormatText       = "text"
+	FormatMarkdownV2 = "markdownv2"
+	FormatHTML       = "html"
+)
+
+// Directions of a transfer, seen from the watched wallets.
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+	DirectionInternal = "internal"
+)
+
+// defaultTemplates are the templates of the event types, used when a notifier
+// does not replace them. They are valid in every format, as all the markup
+// goes through the escape, bold and link helpers.
+var defaultTemplates = map[string]string{
+	EventTransfer: `{{with .Severity}}{{bold (printf "[%s]" .)}} {{end}}{{bold (printf "Transfer on %s" .Transfer.Chain)}}: {{escape .Direction}}
+{{escape .Amount}} {{escape .Symbol}}{{with .USD}} {{escape (printf "(≈ %s)" .)}}{{end}}
+From {{link (.Label .Transfer.From) (.AddressURL .Transfer.From)}}
+To {{link (.Label .Transfer.To) (.AddressURL .Transfer.To)}}
+Transaction {{link (short .Transfer.TransactionHash) .TxURL}}`,
+	EventCorrection: `{{with .Severity}}{{bold (printf "[%s]" .)}} {{end}}{{bold (printf "Correction on %s" .Transfer.Chain)}}: chain reorganization
+Transaction {{link (short .Transfer.TransactionHash) .TxURL}} in orphaned block {{.Transfer.BlockNumber}} was rolled back
+{{escape .Amount}} {{escape .Symbol}} from {{link (.Label .Transfer.From) (.AddressURL .Transfer.From)}} to {{link (.Label .Transfer.To) (.AddressURL .Transfer.To)}}`,
+}
+
+// Event is what the alert templates are executed with.
+type Event struct {
+	// Type is EventTransfer or EventCorrection.
+	Type     string
+	Transfer *models.TrackingInformation
+	Chain    models.ChainConfig
+	// Symbol is the symbol of the token, as configured for the chain if it
+	// is.
+	Symbol string
+	// Direction is DirectionIncoming, DirectionOutgoing or DirectionInternal.
+	Direction string
+	// Severity is the severity of the alert rules matching the transfer, or
+	// empty when there are no rules.
+	Severity string
+}
+
+func (e Event) Subject() string {
+	subject := fmt.Sprintf("Transfer on %s", e.Transfer.Chain)
+	if e.Type == EventCorrection {
+		subject = fmt.Sprintf("Correction on %s", e.Transfer.Chain)
+	}
+	if e.Severity != "" {
+		subject = fmt.Sprintf("[%s] %s", e.Severity, subject)
+	}
+	return subject
+}
+
+// Amount is the amount of the transfer in whole tokens.
+func (e Event) Amount() string {
+	return e.Transfer.Amount.Format(e.Transfer.Decimals)
+}
+
+// USD is the value of the transfer in dollars, or empty if the price of the
+// token is not configured.
+func (e Event) USD() string {
+	value, ok := USDValue(e.Transfer, e.Chain)
+	if !ok {
+		return ""
+	}
+	return "$" + strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// WholeAmount returns the amount of a transfer in whole tokens.
+func WholeAmount(trackingInfo *models.TrackingInformation) float64 {
+	amount, ok := new(big.Float).SetString(trackingInfo.Amount.String())
+	if !ok {
+		return 0
+	}
+	unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(trackingInfo.Decimals)), nil))
+	whole, _ := amount.Quo(amount, unit).Float64()
+	return whole
+}
+
+// USDValue returns the value of a transfer in dollars, if the price of its
+// token is configured for the chain.
+func USDValue(trackingInfo *models.TrackingInformation, chainConfig models.ChainConfig) (float64, bool) {
+	price := chainConfig.PriceUSD
+	if trackingInfo.Token != "" {
+		price = chainConfig.TrackingTokensConfig[trackingInfo.Token].PriceUSD
+	}
+	if price == 0 {
+		return 0, false
+	}
+	return WholeAmount(trackingInfo) * price, true
+}
+
+// TxURL is the explorer page of the transaction, or empty if the chain has
+// no explorer.
+func (e Event) TxURL() string {
+	if e.Chain.Explorer == "" {
+		return ""
+	}
+	return strings.TrimSuffix(e.Chain.Explorer, "/") + "/tx/" + e.Transfer.TransactionHash
+}
+
+// AddressURL is the explorer page of address, or empty if the chain has no
+// explorer.
+func (e Event) AddressURL(address string) string {
+	if e.Chain.Explorer == "" || address == "" {
+		return ""
+	}
+	return strings.TrimSuffix(e.Chain.Explorer, "/") + "/address/" + address
+}
+
+// Label is the shortened address, after its label if it is a party of the
+// transfer with one.
+func (e Event) Label(address string) string {
+	label := ""
+	switch address {
+	case e.Transfer.From:
+		label = e.Transfer.FromLabel
+	case e.Transfer.To:
+		label = e.Transfer.ToLabel
+	}
+	if label == "" {
+		return shortAddress(address)
+	}
+	return fmt.Sprintf("%s (%s)", label, shortAddress(address))
+}
+
+// shortAddress shortens an address or a hash to its first and last 4 hex
+// digits.
+func shortAddress(address string) string {
+	if len(address) <= 12 {
+		return address
+	}
+	return address[:6] + "…" + address[len(address)-4:]
+}
+
+// markdownV2Escaper escapes the characters reserved by Telegram MarkdownV2.
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// markdownV2URLEscaper escapes the characters reserved in MarkdownV2 link
+// URLs.
+var markdownV2URLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// templateFuncs returns the helpers of the templates in format:
+//   - escape, the text escaped for format
+//   - bold, the text escaped and in bold
+//   - link, the text escaped and linking to a URL, just the text without URL
+//   - short, a shortened address or hash
+func templateFuncs(format string) template.FuncMap {
+	escape := func(text string) string { return text }
+	bold := escape
+	link := func(text, url string) string { return text + " (" + url + ")" }
+	switch format {
+	case FormatMarkdownV2:
+		escape = markdownV2Escaper.Replace
+		bold = func(text string) string { return "*" + escape(text) + "*" }
+		link = func(text, url string) string {
+			return "[" + escape(text) + "](" + markdownV2URLEscaper.Replace(url) + ")"
+		}
+	case FormatHTML:
+		escape = html.EscapeString
+		bold = func(text string) string { return "<b>" + escape(text) + "</b>" }
+		link = func(text, url string) string {
+			return `<a href="` + escape(url) + `">` + escape(text) + "</a>"
+		}
+	}
+	return template.FuncMap{
+		"escape": escape,
+		"bold":   bold,
+		"link": func(text, url string) string {
+			if url == "" {
+				return escape(text)
+			}
+			return link(text, url)
+		},
+		"short": shortAddress,
+	}
+}
+
+// Templates render the alerts of a notifier.
+type Templates struct {
+	templates map[string]*template.Template
+	defaults  map[string]*template.Template
+}
+
+// NewTemplates parses the templates of a notifier sending in format, text if
+// empty. templates replace the default template of their event type.
+func NewTemplates(format string, templates map[string]string) (*Templates, error) {
+	if format == "" {
+		format = FormatText
+	}
+	switch format {
+	case FormatText, FormatMarkdownV2, FormatHTML:
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	t := &Templates{
+		templates: make(map[string]*template.Template),
+		defaults:  make(map[string]*template.Template),
+	}
+	for event, text := range defaultTemplates {
+		parsed, err := template.New(event).Funcs(templateFuncs(format)).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse default %s template: %w", event, err)
+		}
+		t.defaults[event] = parsed
+		t.templates[event] = parsed
+	}
+	for event, text := range templates {
+		if _, ok := defaultTemplates[event]; !ok {
+			return nil, fmt.Errorf("unknown template event %q", event)
+		}
+		parsed, err := template.New(event).Funcs(templateFuncs(format)).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s template: %w", event, err)
+		}
+		t.templates[event] = parsed
+	}
+	return t, nil
+}
+
+// Render returns the alert of event. A template failing to execute falls
+// back to the default one, so that the alert is still sent.
+func (t *Templates) Render(event Event) (Message, error) {
+	tmpl, ok := t.templates[event.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown template event %q", event.Type)
+	}
+	var text bytes.Buffer
+	err := tmpl.Execute(&text, event)
+	if err != nil {
+		fmt.Printf("Failed to execute %s template, using the default one: %v\n", event.Type, err)
+		text.Reset()
+		err = t.defaults[event.Type].Execute(&text, event)
+		if err != nil {
+			return Message{}, fmt.Errorf("failed to execute %s template: %w", event.Type, err)
+		}
+	}
+	return Message{
+		Subject:  event.Subject(),
+		Text:     text.String(),
+		Severity: event.Severity,
+		Transfer: event.Transfer,
+	}, nil
+}
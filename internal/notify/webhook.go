@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the body of webhook alerts, as
+// "sha256=" followed by the hex digest.
+const SignatureHeader = "X-Signature-256"
+
+// Webhook posts alerts as JSON Messages to a URL. With a secret, the body is
+// signed in the SignatureHeader so that the receiver can authenticate it.
+type Webhook struct {
+	url    string
+	secret string
+}
+
+func NewWebhook(url string, secret string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	return &Webhook{url: url, secret: secret}, nil
+}
+
+func (n *Webhook) Notify(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	header := make(http.Header)
+	if n.secret != "" {
+		header.Set(SignatureHeader, Sign(n.secret, payload))
+	}
+	return post(ctx, n.url, payload, header)
+}
+
+// Sign returns the signature of payload for the SignatureHeader.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
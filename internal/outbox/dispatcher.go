@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// maxAttempts is how many times a message is tried before it is dead.
+	maxAttempts = 8
+	// pollInterval is how often the outbox is checked for due messages.
+	pollInterval = time.Second
+	// batchSize is how many due messages are delivered per poll.
+	batchSize = 50
+	// baseBackoff is the delay before the first retry, doubled on each
+	// following one up to maxBackoff.
+	baseBackoff = 5 * time.Second
+	maxBackoff  = time.Hour
+	// deliverTimeout bounds a single delivery. As a notifier is given up for
+	// the batch after a failure, a hanging notifier delays the others by at
+	// most deliverTimeout per batch.
+	deliverTimeout = 30 * time.Second
+)
+
+// Dispatcher delivers the messages queued in the outbox with their notifier.
+// The notifiers are delivered to concurrently, the messages of a notifier in
+// order. A message that fails is retried with exponential backoff, and given
+// up as dead after maxAttempts.
+type Dispatcher struct {
+	store     store.TrackingStore
+	notifiers *notify.Registry
+}
+
+func NewDispatcher(trackingStore store.TrackingStore, notifiers *notify.Registry) *Dispatcher {
+	return &Dispatcher{
+		store:     trackingStore,
+		notifiers: notifiers,
+	}
+}
+
+// Run delivers due messages until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		err := d.dispatch(ctx)
+		if err != nil {
+			fmt.Printf("Failed to dispatch outbox: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch delivers the messages that are due, until none is left or ctx is
+// cancelled.
+func (d *Dispatcher) dispatch(ctx context.Context) error {
+	for ctx.Err() == nil {
+		messages, err := d.store.GetDueOutbox(ctx, time.Now(), batchSize)
+		if err != nil {
+			return err
+		}
+		byNotifier := make(map[string][]*models.OutboxMessage)
+		for i := range messages {
+			byNotifier[messages[i].Notifier] = append(byNotifier[messages[i].Notifier], &messages[i])
+		}
+
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			errs   []error
+			failed bool
+		)
+		for _, notifierMessages := range byNotifier {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				delivered, err := d.deliverAll(ctx, notifierMessages)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+				}
+				failed = failed || !delivered
+			}()
+		}
+		wg.Wait()
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		// the messages left to a failing notifier wait for the next poll
+		if failed || len(messages) < batchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// deliverAll delivers the messages of a notifier in order. It stops at the
+// first message that fails, as the next ones are likely to fail too, and
+// reports whether all were delivered.
+func (d *Dispatcher) deliverAll(ctx context.Context, messages []*models.OutboxMessage) (bool, error) {
+	for _, message := range messages {
+		err := d.deliver(ctx, message)
+		if err != nil {
+			return false, err
+		}
+		if message.Status == models.OutboxPending {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deliver sends message and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, message *models.OutboxMessage) error {
+	message.Attempts++
+	notifier, ok := d.notifiers.Get(message.Notifier)
+	if !ok {
+		// the notifier was removed from the config, retrying will not help
+		message.Status = models.OutboxDead
+		message.LastError = fmt.Sprintf("unknown notifier %s", message.Notifier)
+		return d.store.UpdateOutbox(ctx, message)
+	}
+
+	notifyCtx, cancel := context.WithTimeout(ctx, deliverTimeout)
+	err := notifier.Notify(notifyCtx, notify.Message{
+		Subject:  message.Subject,
+		Text:     message.Text,
+		Severity: message.Severity,
+		Transfer: message.Transfer,
+	})
+	cancel()
+	if ctx.Err() != nil {
+		// shutting down, the message is tried again on the next start
+		return nil
+	}
+	now := time.Now()
+	switch {
+	case err == nil:
+		message.Status = models.OutboxDelivered
+		message.LastError = ""
+		message.DeliveredAt = now
+	case message.Attempts >= maxAttempts:
+		fmt.Printf("Giving up alert %d to %s after %d attempts: %v\n", message.ID, message.Notifier, message.Attempts, err)
+		message.Status = models.OutboxDead
+		message.LastError = err.Error()
+	default:
+		message.LastError = err.Error()
+		message.NextAttemptAt = now.Add(backoff(message.Attempts))
+	}
+	return d.store.UpdateOutbox(ctx, message)
+}
+
+// backoff returns the delay before the next attempt of a message that failed
+// attempts times. It doubles with each attempt, with up to 20% of jitter so
+// that messages failing together are not retried together.
+func backoff(attempts int) time.Duration {
+	delay := maxBackoff
+	if attempts <= 20 {
+		delay = min(baseBackoff<<(attempts-1), maxBackoff)
+	}
+	return delay + rand.N(delay/5+1)
+}
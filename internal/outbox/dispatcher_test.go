@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/store"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// queue stores a transfer with count messages to each of notifiers.
+func queue(t *testing.T, trackingStore store.TrackingStore, count int, notifiers ...string) {
+	_, err := trackingStore.SaveTracking(context.Background(), &models.TrackingInformation{Chain: "eth", TransactionHash: "0x1"},
+		func(*models.TrackingInformation) []models.OutboxMessage {
+			var messages []models.OutboxMessage
+			for i := 0; i < count; i++ {
+				for _, name := range notifiers {
+					messages = append(messages, models.OutboxMessage{Notifier: name, Text: "a transfer", Status: models.OutboxPending, NextAttemptAt: time.Now()})
+				}
+			}
+			return messages
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newServer(t *testing.T, handler http.HandlerFunc) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func outboxOf(t *testing.T, trackingStore store.TrackingStore, notifier string) []models.OutboxMessage {
+	messages, err := trackingStore.QueryOutbox(context.Background(), "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var of []models.OutboxMessage
+	for _, message := range messages {
+		if message.Notifier == notifier {
+			of = append(of, message)
+		}
+	}
+	return of
+}
+
+func TestHangingNotifierDoesNotHoldBackOthers(t *testing.T) {
+	release := make(chan struct{})
+	hanging := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+	working := newServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	notifiers, err := notify.NewRegistry(map[string]models.NotifierConfig{
+		"hanging": {Type: "webhook", URL: hanging},
+		"working": {Type: "webhook", URL: working},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackingStore := store.NewMemory()
+	queue(t, trackingStore, 3, "hanging", "working")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewDispatcher(trackingStore, notifiers).Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		delivered, _ := trackingStore.CountOutbox(context.Background(), models.OutboxDelivered)
+		if delivered == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d messages delivered while a notifier hangs, want 3", delivered)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, message := range outboxOf(t, trackingStore, "working") {
+		if message.Status != models.OutboxDelivered {
+			t.Errorf("message %d to working = %s, want delivered", message.ID, message.Status)
+		}
+	}
+}
+
+func TestFailingNotifierIsGivenUpForTheBatch(t *testing.T) {
+	requests := 0
+	failing := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	notifiers, err := notify.NewRegistry(map[string]models.NotifierConfig{
+		"failing": {Type: "webhook", URL: failing},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackingStore := store.NewMemory()
+	queue(t, trackingStore, 3, "failing", "removed")
+
+	err = NewDispatcher(trackingStore, notifiers).dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("%d requests to the failing notifier, want 1", requests)
+	}
+	attempts := 0
+	for _, message := range outboxOf(t, trackingStore, "failing") {
+		attempts += message.Attempts
+		if message.Status != models.OutboxPending {
+			t.Errorf("message %d to failing = %s, want pending", message.ID, message.Status)
+		}
+		if message.Attempts == 1 && !message.NextAttemptAt.After(time.Now()) {
+			t.Errorf("failed message %d is not backed off", message.ID)
+		}
+	}
+	if attempts != 1 {
+		t.Errorf("%d attempts, want 1", attempts)
+	}
+	for _, message := range outboxOf(t, trackingStore, "removed") {
+		if message.Status != models.OutboxDead {
+			t.Errorf("message %d to a removed notifier = %s, want dead", message.ID, message.Status)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: baseBackoff, 2: 2 * baseBackoff, 4: 8 * baseBackoff, 20: maxBackoff, 100: maxBackoff} {
+		delay := backoff(attempts)
+		if delay < want || delay > want+want/5 {
+			t.Errorf("backoff(%d) = %v, want %v to %v", attempts, delay, want, want+want/5)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package rpcpool
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+	"math/big"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	maxAttempts    = 5
+	baseBackoff    = 250 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+	recoveryPeriod = time.Minute
+)
+
+// Pool spreads the RPC calls of a chain over several endpoints. Every call
+// goes to the healthiest endpoint, is retried with exponential backoff on
+// transient errors, and waits for the requests-per-second budget of the
+// endpoint it is sent to. The optional WebSocket endpoint only serves the new
+// heads subscription, with its own budget and health score.
+type Pool struct {
+	chain     string
+	endpoints []*endpoint
+	ws        *endpoint
+}
+
+type endpoint struct {
+	url     string
+	client  *ethclient.Client
+	limiter *rate.Limiter
+
+	mu          sync.Mutex
+	score       float64
+	lastFailure time.Time
+}
+
+func newEndpoint(config models.RPCEndpoint, client *ethclient.Client) *endpoint {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if config.RPS > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.RPS), max(1, int(config.RPS)))
+	}
+	return &endpoint{
+		url:     config.URL,
+		client:  client,
+		limiter: limiter,
+		score:   1,
+	}
+}
+
+// Dial connects to the endpoints, and keeps ws, if its URL is set, for
+// SubscribeNewHead. Environment variables in the URLs are expanded. Endpoints
+// without a budget are not rate limited. An endpoint that cannot be reached
+// is left out, Dial only fails when none can.
+func Dial(ctx context.Context, chain string, endpoints []models.RPCEndpoint, ws models.RPCEndpoint) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no rpc endpoint configured for %s", chain)
+	}
+	pool := &Pool{chain: chain}
+	var errs []error
+	for _, config := range endpoints {
+		client, err := ethclient.DialContext(ctx, os.ExpandEnv(config.URL))
+		if err != nil {
+			fmt.Printf("[%s] Failed to connect to %s, leaving it out: %v\n", chain, config.URL, err)
+			errs = append(errs, fmt.Errorf("failed to connect to %s: %w", config.URL, err))
+			continue
+		}
+		pool.endpoints = append(pool.endpoints, newEndpoint(config, client))
+	}
+	if len(pool.endpoints) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	if ws.URL != "" {
+		pool.ws = newEndpoint(ws, nil)
+	}
+	return pool, nil
+}
+
+func (p *Pool) Close() {
+	for _, e := range p.endpoints {
+		e.client.Close()
+	}
+}
+
+// HasWS reports whether the pool has a WebSocket endpoint.
+func (p *Pool) HasWS() bool {
+	return p.ws != nil
+}
+
+// SubscribeNewHead subscribes to new heads on the WebSocket endpoint and
+// passes them to onHead, until ctx is cancelled or the subscription drops or
+// stays silent for timeout. Connecting waits for the budget of the endpoint,
+// and its health score drops with the subscription.
+func (p *Pool) SubscribeNewHead(ctx context.Context, timeout time.Duration, onHead func(*types.Header)) error {
+	if p.ws == nil {
+		return fmt.Errorf("no websocket endpoint configured for %s", p.chain)
+	}
+	err := p.ws.limiter.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	err = p.ws.subscribeNewHead(ctx, timeout, onHead)
+	if ctx.Err() != nil {
+		return nil
+	}
+	p.ws.failed()
+	return err
+}
+
+func (e *endpoint) subscribeNewHead(ctx context.Context, timeout time.Duration, onHead func(*types.Header)) error {
+	client, err := ethclient.DialContext(ctx, os.ExpandEnv(e.url))
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	headers := make(chan *types.Header)
+	sub, err := client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return err
+		case <-timer.C:
+			return fmt.Errorf("no new head for %v", timeout)
+		case header := <-headers:
+			e.succeeded()
+			onHead(header)
+			timer.Reset(timeout)
+		}
+	}
+}
+
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+	return call(ctx, p, func(client *ethclient.Client) (uint64, error) {
+		return client.BlockNumber(ctx)
+	})
+}
+
+func (p *Pool) ChainID(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*big.Int, error) {
+		return client.ChainID(ctx)
+	})
+}
+
+func (p *Pool) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*types.Block, error) {
+		return client.BlockByNumber(ctx, number)
+	})
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+func (p *Pool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]types.Log, error) {
+		return client.FilterLogs(ctx, query)
+	})
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return call(ctx, p, func(client *ethclient.Client) (*types.Receipt, error) {
+		return client.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// CodeAt and CallContract make the pool a bind.ContractCaller.
+func (p *Pool) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]byte, error) {
+		return client.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (p *Pool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, p, func(client *ethclient.Client) ([]byte, error) {
+		return client.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+func call[T any](ctx context.Context, p *Pool, fn func(client *ethclient.Client) (T, error)) (T, error) {
+	var (
+		zero    T
+		lastErr error
+	)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := min(baseBackoff<<(attempt-1), maxBackoff)
+			backoff += time.Duration(rand.Int63n(int64(backoff) / 2))
+			select {
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		e := p.pick()
+		err := e.limiter.Wait(ctx)
+		if err != nil {
+			return zero, err
+		}
+		result, err := fn(e.client)
+		if err == nil {
+			e.succeeded()
+			return result, nil
+		}
+		if ctx.Err() != nil || !IsTransient(err) {
+			return zero, err
+		}
+		e.failed()
+		lastErr = err
+		fmt.Printf("[%s] RPC call to %s failed, attempt %d/%d: %v\n", p.chain, e.url, attempt+1, maxAttempts, err)
+	}
+	return zero, fmt.Errorf("rpc call failed after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// pick returns the endpoint with the best health score, the first configured
+// one on a tie.
+func (p *Pool) pick() *endpoint {
+	best, bestScore := p.endpoints[0], p.endpoints[0].health()
+	for _, e := range p.endpoints[1:] {
+		score := e.health()
+		if score > bestScore {
+			best, bestScore = e, score
+		}
+	}
+	return best
+}
+
+// health is the score of the endpoint, recovering towards 1 as time passes
+// since its last failure.
+func (e *endpoint) health() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	recovered := float64(time.Since(e.lastFailure)) / float64(recoveryPeriod)
+	if recovered >= 1 {
+		return 1
+	}
+	return e.score + (1-e.score)*recovered
+}
+
+func (e *endpoint) succeeded() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.score = e.score*0.9 + 0.1
+}
+
+func (e *endpoint) failed() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.score = e.score * 0.5
+	e.lastFailure = time.Now()
+}
+
+// IsTransient reports whether a failed call may succeed when retried. Missing
+// data and errors returned by the node for the request itself, such as a
+// reverted call, are final.
+func IsTransient(err error) bool {
+	if errors.Is(err, ethereum.NotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr gethrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	var rpcErr gethrpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32005 is the limit exceeded error of EIP-1474
+		return rpcErr.ErrorCode() == -32005
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package rpcpool
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type rpcError struct {
+	code int
+}
+
+func (e rpcError) Error() string  { return fmt.Sprintf("rpc error %d", e.code) }
+func (e rpcError) ErrorCode() int { return e.code }
+
+func TestIsTransient(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not found", err: ethereum.NotFound, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "deadline", err: context.DeadlineExceeded, want: true},
+		{name: "connection refused", err: errors.New("dial tcp: connection refused"), want: true},
+		{name: "too many requests", err: gethrpc.HTTPError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "server error", err: gethrpc.HTTPError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "unauthorized", err: gethrpc.HTTPError{StatusCode: http.StatusUnauthorized}, want: false},
+		{name: "limit exceeded", err: rpcError{code: -32005}, want: true},
+		{name: "reverted", err: rpcError{code: 3}, want: false},
+		{name: "invalid params", err: rpcError{code: -32602}, want: false},
+		{name: "wrapped limit exceeded", err: fmt.Errorf("rpc call failed after %d attempts: %w", maxAttempts, rpcError{code: -32005}), want: true},
+		{name: "wrapped not found", err: fmt.Errorf("block 1: %w", ethereum.NotFound), want: false},
+	} {
+		if got := IsTransient(test.err); got != test.want {
+			t.Errorf("IsTransient(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// node is a JSON-RPC endpoint answering eth_blockNumber with its block, or
+// failing with HTTP 503 while down.
+type node struct {
+	server *httptest.Server
+	block  uint64
+	down   atomic.Bool
+	calls  atomic.Int32
+}
+
+func newNode(t *testing.T, block uint64) *node {
+	n := &node{block: block}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.calls.Add(1)
+		if n.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var request struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, request.ID, n.block)
+	}))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func dialNodes(t *testing.T, nodes ...*node) *Pool {
+	var endpoints []models.RPCEndpoint
+	for _, n := range nodes {
+		endpoints = append(endpoints, models.RPCEndpoint{URL: n.server.URL})
+	}
+	pool, err := Dial(context.Background(), "eth", endpoints, models.RPCEndpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestFailover(t *testing.T) {
+	primary, backup := newNode(t, 1), newNode(t, 2)
+	primary.down.Store(true)
+	pool := dialNodes(t, primary, backup)
+
+	block, err := pool.BlockNumber(context.Background())
+	if err != nil || block != 2 {
+		t.Fatalf("BlockNumber = %d, %v, want 2 from the backup", block, err)
+	}
+	if primary.calls.Load() != 1 {
+		t.Errorf("%d calls to the failing primary, want 1", primary.calls.Load())
+	}
+	// the backup keeps serving while the primary recovers
+	block, _ = pool.BlockNumber(context.Background())
+	if block != 2 || primary.calls.Load() != 1 {
+		t.Errorf("second BlockNumber = %d after %d calls to the primary, want 2 from the backup", block, primary.calls.Load())
+	}
+}
+
+func TestFailedEndpointIsUsedAgainAfterRecovery(t *testing.T) {
+	primary, backup := newNode(t, 1), newNode(t, 2)
+	primary.down.Store(true)
+	pool := dialNodes(t, primary, backup)
+	_, err := pool.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	primary.down.Store(false)
+	failed := pool.endpoints[0]
+	failed.mu.Lock()
+	// the failure is partly forgotten: the backup is still preferred
+	failed.lastFailure = time.Now().Add(-recoveryPeriod / 4)
+	failed.mu.Unlock()
+	if block, _ := pool.BlockNumber(context.Background()); block != 2 {
+		t.Errorf("BlockNumber = %d while the primary recovers, want 2 from the backup", block)
+	}
+
+	failed.mu.Lock()
+	failed.lastFailure = time.Now().Add(-recoveryPeriod)
+	failed.mu.Unlock()
+	if block, _ := pool.BlockNumber(context.Background()); block != 1 {
+		t.Errorf("BlockNumber = %d after the recovery period, want 1 from the primary", block)
+	}
+}
+
+func TestFinalErrorsAreNotRetried(t *testing.T) {
+	calls := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var request struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted"}}`, request.ID)
+	}))
+	defer server.Close()
+	pool, err := Dial(context.Background(), "eth", []models.RPCEndpoint{{URL: server.URL}}, models.RPCEndpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	_, err = pool.BlockNumber(context.Background())
+	if err == nil || IsTransient(err) {
+		t.Errorf("BlockNumber = %v, want the final error", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("%d calls, want 1", calls.Load())
+	}
+}
+
+func TestDialLeavesOutUnreachableEndpoints(t *testing.T) {
+	working := newNode(t, 3)
+	pool, err := Dial(context.Background(), "eth", []models.RPCEndpoint{{URL: "unix:///nonexistent/socket"}, {URL: working.server.URL}}, models.RPCEndpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if len(pool.endpoints) != 1 {
+		t.Fatalf("%d endpoints, want the reachable one", len(pool.endpoints))
+	}
+	if _, err := Dial(context.Background(), "eth", []models.RPCEndpoint{{URL: "unix:///nonexistent/socket"}}, models.RPCEndpoint{}); err == nil {
+		t.Error("Dial without any reachable endpoint succeeded, want an error")
+	}
+}
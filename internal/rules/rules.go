@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"strings"
+	"sync"
+)
+
+// TokenNative is the token of the rules matching native token transfers.
+const TokenNative = "native"
+
+// severities ranks the severities, the highest last.
+var severities = map[string]int{
+	models.SeverityInfo:     1,
+	models.SeverityWarning:  2,
+	models.SeverityCritical: 3,
+}
+
+// ValidSeverity reports whether severity is a known severity.
+func ValidSeverity(severity string) bool {
+	_, ok := severities[severity]
+	return ok
+}
+
+// Transfer is a transfer as rules see it.
+type Transfer struct {
+	Info *models.TrackingInformation
+	// Direction is seen from the watched wallets, see notify.DirectionIncoming.
+	Direction string
+	// USD is the value of the transfer in dollars, if HasUSD.
+	USD    float64
+	HasUSD bool
+	// Amount is the exact amount of the transfer in whole tokens.
+	Amount models.Decimal
+}
+
+// Engine holds the alert rules. It is safe for concurrent use, and is updated
+// live by the API.
+type Engine struct {
+	mu    sync.RWMutex
+	rules []models.AlertRule
+}
+
+func NewEngine(rules []models.AlertRule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Put adds rule, or replaces the rule with the same ID.
+func (e *Engine) Put(rule models.AlertRule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range e.rules {
+		if e.rules[i].ID == rule.ID {
+			e.rules[i] = rule
+			return
+		}
+	}
+	e.rules = append(e.rules, rule)
+}
+
+// Remove removes the rule with ID id.
+func (e *Engine) Remove(id int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range e.rules {
+		if e.rules[i].ID == id {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+			return
+		}
+	}
+}
+
+// Route returns the notifiers to alert of transfer, with the severity of the
+// alert. Without any enabled rule, these are defaultNames, without severity.
+// Otherwise each matching rule adds its notifiers, or defaultNames if it
+// names none, and a notifier gets the highest severity of its rules; a
+// transfer matching no rule is not alerted.
+func (e *Engine) Route(transfer Transfer, defaultNames []string) map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	routes := make(map[string]string)
+	active := false
+	for _, rule := range e.rules {
+		if rule.Disabled {
+			continue
+		}
+		active = true
+		if !Matches(rule, transfer) {
+			continue
+		}
+		names := rule.Notifiers
+		if len(names) == 0 {
+			names = defaultNames
+		}
+		for _, name := range names {
+			if severity, ok := routes[name]; !ok || severities[rule.Severity] > severities[severity] {
+				routes[name] = rule.Severity
+			}
+		}
+	}
+	if !active {
+		for _, name := range defaultNames {
+			routes[name] = ""
+		}
+	}
+	return routes
+}
+
+// Matches reports whether transfer meets every condition of rule.
+func Matches(rule models.AlertRule, transfer Transfer) bool {
+	info := transfer.Info
+	if rule.Chain != "" && rule.Chain != info.Chain {
+		return false
+	}
+	if rule.Token != "" {
+		token := strings.ToLower(rule.Token)
+		if token == TokenNative {
+			token = ""
+		}
+		if token != info.Token {
+			return false
+		}
+	}
+	if rule.Direction != "" && rule.Direction != transfer.Direction {
+		return false
+	}
+	if rule.Counterparty != "" && !matchCounterparty(strings.ToLower(rule.Counterparty), transfer) {
+		return false
+	}
+	if rule.MinAmount != nil && transfer.Amount.Compare(*rule.MinAmount) < 0 {
+		return false
+	}
+	if rule.MaxAmount != nil && transfer.Amount.Compare(*rule.MaxAmount) > 0 {
+		return false
+	}
+	if (rule.MinUSD != nil || rule.MaxUSD != nil) && !transfer.HasUSD {
+		return false
+	}
+	if rule.MinUSD != nil && transfer.USD < *rule.MinUSD {
+		return false
+	}
+	if rule.MaxUSD != nil && transfer.USD > *rule.MaxUSD {
+		return false
+	}
+	return true
+}
+
+// matchCounterparty reports whether address is the other party of transfer:
+// the sender of incoming transfers, the recipient of outgoing ones, and
+// either party of internal ones.
+func matchCounterparty(address string, transfer Transfer) bool {
+	switch transfer.Direction {
+	case notify.DirectionIncoming:
+		return transfer.Info.From == address
+	case notify.DirectionOutgoing:
+		return transfer.Info.To == address
+	}
+	return transfer.Info.From == address || transfer.Info.To == address
+}
@@ -0,0 +1,63 @@
+package rules
+
+import (
+	"Intermediate_web3/internal/models"
+	"math/big"
+	"testing"
+)
+
+func decimal(t *testing.T, s string) *models.Decimal {
+	d, err := models.ParseDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &d
+}
+
+func TestMatchesAmountsExactly(t *testing.T) {
+	// 0.1 + 0.2 tokens of 18 decimals, which float64 sees above 0.3
+	raw, _ := new(big.Int).SetString("300000000000000000", 10)
+	info := &models.TrackingInformation{Chain: "eth", Amount: models.NewBigInt(raw), Decimals: 18}
+	transfer := Transfer{Info: info, Amount: info.Amount.Whole(info.Decimals)}
+
+	for _, test := range []struct {
+		min, max string
+		want     bool
+	}{
+		{min: "0.3", want: true},
+		{max: "0.3", want: true},
+		{min: "0.300000000000000001", want: false},
+		{max: "0.299999999999999999", want: false},
+		{min: "0.1", max: "0.3", want: true},
+	} {
+		rule := models.AlertRule{}
+		if test.min != "" {
+			rule.MinAmount = decimal(t, test.min)
+		}
+		if test.max != "" {
+			rule.MaxAmount = decimal(t, test.max)
+		}
+		if got := Matches(rule, transfer); got != test.want {
+			t.Errorf("Matches(min %s, max %s) = %v, want %v", test.min, test.max, got, test.want)
+		}
+	}
+}
+
+func TestDecimalRoundTrip(t *testing.T) {
+	for input, want := range map[string]string{
+		"1.5":                  "1.5",
+		"0.000000000000000001": "0.000000000000000001",
+		"100":                  "100",
+		"1e3":                  "1000",
+		"-2.50":                "-2.5",
+	} {
+		if got := decimal(t, input).String(); got != want {
+			t.Errorf("ParseDecimal(%s) = %s, want %s", input, got, want)
+		}
+	}
+	for _, input := range []string{"", "1/3", "0x10", "abc"} {
+		if _, err := models.ParseDecimal(input); err == nil {
+			t.Errorf("ParseDecimal(%q) succeeded, want an error", input)
+		}
+	}
+}
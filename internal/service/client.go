@@ -0,0 +1,39 @@
+package service
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/rpcpool"
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"time"
+)
+
+// chainClient is what the tracker reads a chain with, an rpcpool.Pool outside
+// of tests.
+type chainClient interface {
+	bind.ContractCaller
+	BlockNumber(ctx context.Context) (uint64, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	// HasWS and SubscribeNewHead follow the head over WebSocket, see
+	// rpcpool.Pool.SubscribeNewHead.
+	HasWS() bool
+	SubscribeNewHead(ctx context.Context, timeout time.Duration, onHead func(*types.Header)) error
+	Close()
+}
+
+// dialPool connects to the endpoints of a chain with an rpcpool.Pool.
+func dialPool(ctx context.Context, chainConfig models.ChainConfig) (chainClient, error) {
+	pool, err := rpcpool.Dial(ctx, chainConfig.Chain, chainConfig.Endpoints(), models.RPCEndpoint{URL: chainConfig.WS, RPS: chainConfig.WSRPS})
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
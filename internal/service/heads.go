@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/core/types"
+	"sync"
+	"time"
+)
+
+const (
+	minPollInterval = 250 * time.Millisecond
+	maxPollInterval = 12 * time.Second
+	// resubscribeInterval is how long the watcher polls after a subscription
+	// dropped before subscribing again.
+	resubscribeInterval = time.Minute
+	// subscriptionTimeout is how long a subscription may stay silent before it
+	// is considered dropped.
+	subscriptionTimeout = time.Minute
+)
+
+// headWatcher follows the head block number of a chain, either from a
+// newHeads subscription over WebSocket or by polling the RPC endpoint.
+type headWatcher struct {
+	chain    string
+	client   chainClient
+	interval time.Duration
+
+	mu      sync.Mutex
+	latest  uint64
+	changed chan struct{}
+}
+
+func newHeadWatcher(chain string, client chainClient) *headWatcher {
+	return &headWatcher{
+		chain:    chain,
+		client:   client,
+		interval: time.Second,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Wait blocks until the head is above after and returns it.
+func (w *headWatcher) Wait(ctx context.Context, after uint64) (uint64, error) {
+	for {
+		w.mu.Lock()
+		latest, changed := w.latest, w.changed
+		w.mu.Unlock()
+		if latest > after {
+			return latest, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// set records a new head and wakes up waiters. It reports whether the head
+// moved forward.
+func (w *headWatcher) set(head uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if head <= w.latest {
+		return false
+	}
+	w.latest = head
+	close(w.changed)
+	w.changed = make(chan struct{})
+	return true
+}
+
+// run follows the head until ctx is cancelled. With a WebSocket endpoint it
+// subscribes to new heads and falls back to polling while the subscription is
+// down. The tracker backfills any blocks missed meanwhile, since it always
+// processes from its checkpoint up to the head.
+func (w *headWatcher) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if !w.client.HasWS() {
+			w.poll(ctx, time.Time{})
+			return
+		}
+		err := w.client.SubscribeNewHead(ctx, subscriptionTimeout, func(header *types.Header) {
+			w.set(header.Number.Uint64())
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("[%s] Head subscription dropped, polling instead: %v\n", w.chain, err)
+		w.poll(ctx, time.Now().Add(resubscribeInterval))
+	}
+}
+
+// poll asks for the head block number until ctx is cancelled or until, if not
+// zero, is reached. The interval adapts to about half of the observed block
+// time and backs off while the head does not move or the endpoint fails.
+func (w *headWatcher) poll(ctx context.Context, until time.Time) {
+	var (
+		lastHead    uint64
+		lastAdvance time.Time
+	)
+	for until.IsZero() || time.Now().Before(until) {
+		head, err := w.client.BlockNumber(ctx)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("[%s] Failed to poll head block: %v\n", w.chain, err)
+			w.interval = min(w.interval*2, maxPollInterval)
+		case w.set(head):
+			now := time.Now()
+			if !lastAdvance.IsZero() && head > lastHead {
+				blockTime := now.Sub(lastAdvance) / time.Duration(head-lastHead)
+				w.interval = max(min(blockTime/2, maxPollInterval), minPollInterval)
+			}
+			lastHead, lastAdvance = head, now
+		default:
+			w.interval = min(w.interval*3/2, maxPollInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	token "Intermediate_web3/internal/build"
+	"Intermediate_web3/internal/models"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"slices"
+)
+
+var (
+	errRangeChanged = errors.New("chain reorganized while reading the block range")
+
+	// transferFilterer only decodes logs, so it is not bound to a contract.
+	transferFilterer, _ = token.NewStoreFilterer(common.Address{}, nil)
+	transferTopic       = mustTransferTopic()
+)
+
+func mustTransferTopic() common.Hash {
+	storeAbi, err := token.StoreMetaData.GetAbi()
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse ERC20 ABI: %v", err))
+	}
+	return storeAbi.Events["Transfer"].ID
+}
+
+// filterTransferLogs returns the Transfer logs of the tracked tokens in the
+// blocks from..to that involve a watched wallet, ordered as on chain.
+func (t *Tracker) filterTransferLogs(ctx context.Context, client chainClient, chainConfig models.ChainConfig, from, to uint64) ([]types.Log, error) {
+	tokenAddresses := t.watchlist.Tokens(chainConfig.Chain)
+	wallets := t.watchlist.Wallets(chainConfig.Chain)
+	// an empty address list would match every contract
+	if len(tokenAddresses) == 0 || len(wallets) == 0 {
+		return nil, nil
+	}
+	tokens := make([]common.Address, 0, len(tokenAddresses))
+	for _, tokenAddress := range tokenAddresses {
+		tokens = append(tokens, common.HexToAddress(tokenAddress))
+	}
+	users := make([]common.Hash, 0, len(wallets))
+	for _, wallet := range wallets {
+		users = append(users, common.BytesToHash(common.HexToAddress(wallet).Bytes()))
+	}
+
+	// topics are ANDed across positions, so sent and received transfers are
+	// queried separately
+	var logs []types.Log
+	seen := make(map[string]bool)
+	for _, topics := range [][][]common.Hash{
+		{{transferTopic}, users},
+		{{transferTopic}, nil, users},
+	} {
+		result, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: tokens,
+			Topics:    topics,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter logs: %w", err)
+		}
+		for _, log := range result {
+			// self transfers match both queries
+			key := fmt.Sprintf("%s-%d", log.TxHash.Hex(), log.Index)
+			if log.Removed || seen[key] {
+				continue
+			}
+			seen[key] = true
+			logs = append(logs, log)
+		}
+	}
+
+	slices.SortFunc(logs, func(a, b types.Log) int {
+		if a.BlockNumber != b.BlockNumber {
+			return cmp.Compare(a.BlockNumber, b.BlockNumber)
+		}
+		return cmp.Compare(a.Index, b.Index)
+	})
+	return logs, nil
+}
@@ -1,34 +1,105 @@
 package service
 
 import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/rules"
+	"Intermediate_web3/internal/store"
 	"fmt"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"os"
-	"strconv"
+	"sort"
+	"strings"
+	"time"
 )
 
-func SendMessage(message string) error {
-	TelegramBotToken := os.Getenv("TELEGRAM_BOT_TOKEN")
-	groupIdStr := os.Getenv("TELEGRAM_CHAT_ID")
-	bot, err := tgbotapi.NewBotAPI(TelegramBotToken)
-	if err != nil {
-		fmt.Println(err)
-	}
-	groupId, err := strconv.ParseInt(groupIdStr, 10, 64)
-	if err != nil {
-		return fmt.Errorf("failed to parse GROUPCHAT_ID: %v", err)
+// alertFunc returns the store.AlertFunc queueing the alert of event type
+// eventType. The notifiers alerted, and the severity of the alert, are decided
+// by the alert rules, see rules.Engine.Route; the default notifiers are the
+// ones of the chain and of the wallets of the transfer, see
+// models.ChainConfig.Notify. Each notifier gets its own outbox message,
+// rendered with its own templates, so that one failing notifier does not hold
+// back the others.
+func (t *Tracker) alertFunc(chainConfig models.ChainConfig, eventType string) store.AlertFunc {
+	return func(trackingInfo *models.TrackingInformation) []models.OutboxMessage {
+		names := append([]string(nil), chainConfig.Notify...)
+		for _, wallet := range chainConfig.Wallets {
+			address := strings.ToLower(wallet.Address)
+			if address == trackingInfo.From || address == trackingInfo.To {
+				names = append(names, wallet.Notify...)
+			}
+		}
+
+		direction := t.direction(trackingInfo)
+		usd, hasUSD := notify.USDValue(trackingInfo, chainConfig)
+		routes := t.rules.Route(rules.Transfer{
+			Info:      trackingInfo,
+			Direction: direction,
+			USD:       usd,
+			HasUSD:    hasUSD,
+			Amount:    trackingInfo.Amount.Whole(trackingInfo.Decimals),
+		}, t.notifiers.Select(names))
+		if len(routes) == 0 {
+			return nil
+		}
+		routed := make([]string, 0, len(routes))
+		for name := range routes {
+			routed = append(routed, name)
+		}
+		sort.Strings(routed)
+
+		now := time.Now()
+		var messages []models.OutboxMessage
+		for _, name := range t.notifiers.Select(routed) {
+			alert, err := t.notifiers.Render(name, notify.Event{
+				Type:      eventType,
+				Transfer:  trackingInfo,
+				Chain:     chainConfig,
+				Symbol:    tokenSymbol(trackingInfo, chainConfig),
+				Direction: direction,
+				Severity:  routes[name],
+			})
+			if err != nil {
+				fmt.Printf("Failed to render alert for %s: %v\n", name, err)
+				continue
+			}
+			messages = append(messages, models.OutboxMessage{
+				Notifier:      name,
+				Subject:       alert.Subject,
+				Text:          alert.Text,
+				Transfer:      alert.Transfer,
+				Severity:      alert.Severity,
+				Status:        models.OutboxPending,
+				NextAttemptAt: now,
+			})
+		}
+		return messages
 	}
-	chatId, err := strconv.Atoi(strconv.FormatInt(groupId, 10))
-	if err != nil {
-		fmt.Println(err)
+}
+
+// tokenSymbol returns the symbol of the token of a transfer, as configured
+// for the chain if it is.
+func tokenSymbol(trackingInfo *models.TrackingInformation, chainConfig models.ChainConfig) string {
+	switch trackingInfo.Type {
+	case TypeTokenNative:
+		return chainConfig.ChainSymbol
+	case TypeTokenERC20:
+		tokenTrackingConfig, ok := chainConfig.TrackingTokensConfig[trackingInfo.Token]
+		if ok && tokenTrackingConfig.Symbol != "" {
+			return tokenTrackingConfig.Symbol
+		}
 	}
-	// Create a new message to send
-	msg := tgbotapi.NewMessage(int64(chatId), message)
+	return trackingInfo.Symbol
+}
 
-	// Send the message
-	_, err = bot.Send(msg)
-	if err != nil {
-		fmt.Println(err)
+// direction tells whether a transfer enters or leaves the watched wallets of
+// its chain, or moves between two of them.
+func (t *Tracker) direction(trackingInfo *models.TrackingInformation) string {
+	from := t.checkUserTracked(trackingInfo.From, trackingInfo.Chain)
+	to := t.checkUserTracked(trackingInfo.To, trackingInfo.Chain)
+	switch {
+	case from && to:
+		return notify.DirectionInternal
+	case to:
+		return notify.DirectionIncoming
 	}
-	return nil
+	return notify.DirectionOutgoing
 }
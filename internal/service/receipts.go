@@ -0,0 +1,61 @@
+package service
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"time"
+)
+
+// receiptCache fetches the receipts of the tracked transactions of a block,
+// once per transaction even when it has several tracked transfers.
+type receiptCache struct {
+	client   chainClient
+	receipts map[common.Hash]*types.Receipt
+}
+
+func newReceiptCache(client chainClient) *receiptCache {
+	return &receiptCache{
+		client:   client,
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+}
+
+func (r *receiptCache) get(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	receipt, ok := r.receipts[txHash]
+	if ok {
+		return receipt, nil
+	}
+	receipt, err := r.client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	r.receipts[txHash] = receipt
+	return receipt, nil
+}
+
+// setBlockDetails fills the block and gas details of a transfer. It fails if
+// the receipt of the transaction cannot be fetched, so that the block is
+// processed again rather than stored without gas details.
+func setBlockDetails(ctx context.Context, trackingInfo *models.TrackingInformation, block *types.Block, tx *types.Transaction, receipts *receiptCache) error {
+	trackingInfo.BlockNumber = block.NumberU64()
+	trackingInfo.BlockHash = block.Hash().Hex()
+	trackingInfo.BlockTime = time.Unix(int64(block.Time()), 0).UTC()
+
+	receipt, err := receipts.get(ctx, tx.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get transaction receipt of %s: %w", tx.Hash().Hex(), err)
+	}
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+	trackingInfo.TransactionIndex = receipt.TransactionIndex
+	trackingInfo.GasUsed = receipt.GasUsed
+	trackingInfo.EffectiveGasPrice = models.NewBigInt(gasPrice)
+	trackingInfo.Fee = models.NewBigInt(new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed)))
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+)
+
+// maxReorgDepth is how many recent block hashes are kept per chain, and so
+// the deepest reorganization that can be rolled back.
+const maxReorgDepth = 128
+
+// findReorg checks that block extends the last recorded block of the chain.
+// When it does not, it walks back over the recorded blocks until one matches
+// the canonical chain again and returns that common ancestor. It fails when
+// no ancestor is found within maxReorgDepth blocks, as rolling back to a
+// block that was reorganized too would leave orphaned transfers behind.
+func (t *Tracker) findReorg(ctx context.Context, client chainClient, chain string, block *types.Block) (uint64, bool, error) {
+	number := block.NumberU64()
+	if number == 0 {
+		return 0, false, nil
+	}
+	parent, found, err := t.store.GetBlock(ctx, chain, number-1)
+	if err != nil {
+		return 0, false, err
+	}
+	if !found || parent.BlockHash == block.ParentHash().Hex() {
+		return 0, false, nil
+	}
+
+	for ancestor := number - 1; ancestor > 0 && number-ancestor < maxReorgDepth; {
+		ancestor--
+		recorded, found, err := t.store.GetBlock(ctx, chain, ancestor)
+		if err != nil {
+			return 0, false, err
+		}
+		if !found {
+			return 0, false, fmt.Errorf("chain reorganization below block %d, the oldest recorded one", ancestor+1)
+		}
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(ancestor))
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get header %d: %w", ancestor, err)
+		}
+		if header.Hash().Hex() == recorded.BlockHash {
+			return ancestor, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("chain reorganization deeper than %d blocks at block %d", maxReorgDepth, number)
+}
+
+// rollbackReorg drops everything tracked above the common ancestor and sends
+// a correction for each transfer that had already been announced.
+func (t *Tracker) rollbackReorg(ctx context.Context, chainConfig models.ChainConfig, ancestor uint64) error {
+	fmt.Printf("Chain reorganization on %s, rolling back to block %d\n", chainConfig.Chain, ancestor)
+	_, err := t.store.RollbackBlocks(ctx, chainConfig.Chain, ancestor, t.alertFunc(chainConfig, notify.EventCorrection))
+	return err
+}
+
+// recordBlock stores the hash of a processed block and forgets blocks that are
+// too old to be reorganized or to settle pending transfers.
+func (t *Tracker) recordBlock(ctx context.Context, chainConfig models.ChainConfig, block *types.Block) error {
+	err := t.store.SaveBlock(ctx, &models.TrackedBlock{
+		Chain:       chainConfig.Chain,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		ParentHash:  block.ParentHash().Hex(),
+	})
+	if err != nil {
+		return err
+	}
+	retention := uint64(maxReorgDepth)
+	if chainConfig.Confirmations >= retention {
+		retention = chainConfig.Confirmations + 1
+	}
+	if block.NumberU64() > retention {
+		return t.store.PruneBlocks(ctx, chainConfig.Chain, block.NumberU64()-retention)
+	}
+	return nil
+}
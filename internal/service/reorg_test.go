@@ -0,0 +1,85 @@
+package service
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/store"
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+)
+
+// fakeChain serves the headers of a canonical chain.
+type fakeChain struct {
+	chainClient
+}
+
+func canonicalHeader(number uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte("canonical")}
+}
+
+func (fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return canonicalHeader(number.Uint64()), nil
+}
+
+// recordBlocks records the blocks from..to, canonical up to canonicalTo and
+// orphaned above.
+func recordBlocks(t *testing.T, trackingStore store.TrackingStore, from, to, canonicalTo uint64) {
+	for number := from; number <= to; number++ {
+		hash := canonicalHeader(number).Hash().Hex()
+		if number > canonicalTo {
+			hash = common.BigToHash(new(big.Int).SetUint64(number)).Hex()
+		}
+		err := trackingStore.SaveBlock(context.Background(), &models.TrackedBlock{Chain: "eth", BlockNumber: number, BlockHash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindReorg(t *testing.T) {
+	newBlock := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(300), ParentHash: common.HexToHash("0x1")})
+	tests := []struct {
+		name           string
+		from           uint64
+		canonicalTo    uint64
+		wantAncestor   uint64
+		wantReorgError bool
+	}{
+		{name: "shallow", from: 100, canonicalTo: 295, wantAncestor: 295},
+		{name: "deepest", from: 100, canonicalTo: 300 - maxReorgDepth, wantAncestor: 300 - maxReorgDepth},
+		{name: "deeper than the limit", from: 100, canonicalTo: 299 - maxReorgDepth, wantReorgError: true},
+		{name: "below the recorded blocks", from: 290, canonicalTo: 250, wantReorgError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trackingStore := store.NewMemory()
+			recordBlocks(t, trackingStore, test.from, 299, test.canonicalTo)
+			tracker := &Tracker{store: trackingStore}
+
+			ancestor, reorged, err := tracker.findReorg(context.Background(), fakeChain{}, "eth", newBlock)
+			if test.wantReorgError {
+				if err == nil {
+					t.Fatalf("findReorg = %d, %v, want an error", ancestor, reorged)
+				}
+				return
+			}
+			if err != nil || !reorged || ancestor != test.wantAncestor {
+				t.Fatalf("findReorg = %d, %v, %v, want %d, true", ancestor, reorged, err, test.wantAncestor)
+			}
+		})
+	}
+}
+
+func TestFindReorgWithoutReorg(t *testing.T) {
+	trackingStore := store.NewMemory()
+	recordBlocks(t, trackingStore, 1, 9, 9)
+	tracker := &Tracker{store: trackingStore}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), ParentHash: canonicalHeader(9).Hash()})
+
+	_, reorged, err := tracker.findReorg(context.Background(), fakeChain{}, "eth", block)
+	if err != nil || reorged {
+		t.Fatalf("findReorg = %v, %v, want no reorganization", reorged, err)
+	}
+}
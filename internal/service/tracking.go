@@ -1,124 +1,488 @@
 package service
 
 import (
-	"Intermediate_web3/internal/api"
 	token "Intermediate_web3/internal/build"
 	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/rules"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/tokenmeta"
+	"Intermediate_web3/internal/watchlist"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"math/big"
-	"os"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	TypeTokenNative = "NativeToken"
 	TypeTokenERC20  = "Erc20Token"
-	BlockNumber     = 20688778
+	// nativeDecimals are the decimals of the native token of EVM chains.
+	nativeDecimals = 18
+	// maxBlockRange is how many blocks are read in one pass while catching up.
+	maxBlockRange = 100
+	// restartDelay is how long a failed chain tracker waits before restarting.
+	restartDelay = 30 * time.Second
+	// retryDelay is how long the tracker waits for blocks the endpoint does
+	// not serve yet.
+	retryDelay = 2 * time.Second
+	// rangeTimeout bounds the processing of a block range, which goes on
+	// after shutdown is requested so that shutdown does not wait forever on
+	// a stuck endpoint or database.
+	rangeTimeout = 2 * time.Minute
 )
 
-var (
-	mapListTracking = make(map[string]struct {
-		MapListTokens map[string]bool
-		UsersTracking string
-	})
-	config *models.ChainConfig
-	ctx    = context.Background()
-)
+// Tracker scans the configured chains for transfers of the watched wallets
+// and records them in its store.
+type Tracker struct {
+	store     store.TrackingStore
+	watchlist *watchlist.Registry
+	tokens    *tokenmeta.Service
+	notifiers *notify.Registry
+	rules     *rules.Engine
+	// dial connects to the endpoints of a chain.
+	dial   func(ctx context.Context, chainConfig models.ChainConfig) (chainClient, error)
+	config atomic.Pointer[models.Config]
+	// reload signals TokenTracking that config was replaced.
+	reload chan struct{}
+	// heads maps chains to the last head block seen.
+	heads sync.Map
+	// clients maps the running chains to their connection.
+	clients sync.Map
+}
+
+// runningChain is the tracker of a chain started by TokenTracking.
+type runningChain struct {
+	config models.ChainConfig
+	stop   context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTracker returns a tracker of the chains of config. The wallets and
+// tokens of the config file are added to registry, which can be changed while
+// the tracker runs. tokens resolves the metadata of the tracked tokens,
+// notifiers are the notifiers of config, and ruleEngine decides which
+// transfers are alerted.
+func NewTracker(config *models.Config, trackingStore store.TrackingStore, registry *watchlist.Registry, tokens *tokenmeta.Service, notifiers *notify.Registry, ruleEngine *rules.Engine) *Tracker {
+	t := &Tracker{
+		store:     trackingStore,
+		watchlist: registry,
+		tokens:    tokens,
+		notifiers: notifiers,
+		rules:     ruleEngine,
+		dial:      dialPool,
+		reload:    make(chan struct{}, 1),
+	}
+	t.config.Store(config)
+	for _, chainConfig := range config.Chains {
+		registry.SetConfig(chainConfig)
+	}
+	return t
+}
 
-func init() {
-	var err error
-	config, err = loadConfig()
+// Reload replaces the config of the tracker. Chains that were added or whose
+// config changed are (re)started, removed chains are stopped.
+func (t *Tracker) Reload(config *models.Config) {
+	err := t.notifiers.Reload(config.Notifiers)
 	if err != nil {
-		fmt.Println("load config err:", err)
-		return
+		fmt.Printf("Failed to reload notifiers, keeping the previous ones: %v\n", err)
 	}
-	mapListTracking[config.Chain] = struct {
-		MapListTokens map[string]bool
-		UsersTracking string
-	}{
-		MapListTokens: make(map[string]bool),
-		UsersTracking: config.UsersTracking,
+	t.config.Store(config)
+	select {
+	case t.reload <- struct{}{}:
+	default:
 	}
+}
 
-	for _, tokenTracking := range config.ListTokensTracking {
-		mapListTracking[config.Chain].MapListTokens[strings.ToLower(tokenTracking)] = true
+// Chains returns the names of the configured chains.
+func (t *Tracker) Chains() []string {
+	var chains []string
+	for _, chainConfig := range t.config.Load().Chains {
+		chains = append(chains, chainConfig.Chain)
 	}
+	return chains
 }
 
-func loadConfig() (*models.ChainConfig, error) {
-	byteValue, err := os.ReadFile("config.json")
-	if err != nil {
-		return nil, fmt.Errorf("error reading config file: %w", err)
+// Head returns the last head block seen on chain, if any.
+func (t *Tracker) Head(chain string) (uint64, bool) {
+	head, ok := t.heads.Load(chain)
+	if !ok {
+		return 0, false
 	}
+	return head.(uint64), true
+}
 
-	err = json.Unmarshal(byteValue, &config)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+// ResolveToken returns the metadata of the token of chain at address, read
+// through the connection of the running tracker of chain. The error wraps
+// tokenmeta.ErrNotToken when the contract is not an ERC-20 token.
+func (t *Tracker) ResolveToken(ctx context.Context, chain string, address string) (models.Token, error) {
+	client, ok := t.clients.Load(chain)
+	if !ok {
+		return models.Token{}, fmt.Errorf("chain %s is not connected", chain)
 	}
-	return config, nil
+	return t.tokens.Resolve(ctx, chain, client.(chainClient), common.HexToAddress(address))
 }
 
-func TokenTracking() error {
-	if config.Chain == "" {
+// TokenTracking runs one block tracker per configured chain until ctx is
+// cancelled. A block range that is already being processed when ctx is
+// cancelled is finished first, so its notifications and database writes are
+// not lost. startBlocks maps chains to a block overriding both the stored
+// checkpoint and the configured start block, see ParseStartBlocks.
+func (t *Tracker) TokenTracking(ctx context.Context, startBlocks map[string]uint64) error {
+	config := t.config.Load()
+	if config == nil || len(config.Chains) == 0 {
 		return fmt.Errorf("chain configuration not found")
 	}
-	err := handlerTracking(*config)
-	if err != nil {
-		return err
+
+	running := make(map[string]*runningChain)
+	defer func() {
+		for _, chain := range running {
+			chain.stop()
+			<-chain.done
+		}
+	}()
+	t.applyConfig(ctx, running, config, startBlocks)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.reload:
+			t.applyConfig(ctx, running, t.config.Load(), nil)
+		}
+	}
+}
+
+// applyConfig starts the trackers of the chains of config that are not
+// running yet, restarts the ones whose config changed and stops the ones
+// that are no longer configured.
+func (t *Tracker) applyConfig(ctx context.Context, running map[string]*runningChain, config *models.Config, startBlocks map[string]uint64) {
+	configured := make(map[string]bool)
+	for _, chainConfig := range config.Chains {
+		configured[chainConfig.Chain] = true
+		t.watchlist.SetConfig(chainConfig)
+
+		chain, ok := running[chainConfig.Chain]
+		if ok && reflect.DeepEqual(chain.config, chainConfig) {
+			continue
+		}
+		if ok {
+			fmt.Printf("[%s] Config changed, restarting tracker\n", chainConfig.Chain)
+			chain.stop()
+			<-chain.done
+		}
+
+		startBlock, ok := startBlocks[chainConfig.Chain]
+		if !ok {
+			startBlock = startBlocks[""]
+		}
+		chainCtx, stop := context.WithCancel(ctx)
+		chain = &runningChain{config: chainConfig, stop: stop, done: make(chan struct{})}
+		running[chainConfig.Chain] = chain
+		go func(chainConfig models.ChainConfig, startBlock uint64, done chan struct{}) {
+			defer close(done)
+			t.runChainTracking(chainCtx, chainConfig, startBlock)
+		}(chainConfig, startBlock, chain.done)
+	}
+
+	for name, chain := range running {
+		if configured[name] {
+			continue
+		}
+		fmt.Printf("[%s] Chain removed from config, stopping tracker\n", name)
+		chain.stop()
+		<-chain.done
+		delete(running, name)
+		t.watchlist.RemoveChain(name)
 	}
-	return nil
 }
 
-func handlerTracking(chainConfig models.ChainConfig) error {
-	client, err := ethclient.Dial(os.Getenv("RPC"))
+// runChainTracking tracks a single chain, restarting the tracker after a delay
+// when it fails so that one chain cannot stop the others.
+func (t *Tracker) runChainTracking(ctx context.Context, chainConfig models.ChainConfig, startBlock uint64) {
+	for {
+		err := t.handlerTracking(ctx, chainConfig, startBlock)
+		if err == nil {
+			return
+		}
+		fmt.Printf("[%s] Tracker failed, restarting in %v: %v\n", chainConfig.Chain, restartDelay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(restartDelay):
+		}
+		// resume from the checkpoint after a restart
+		startBlock = 0
+	}
+}
+
+// ParseStartBlocks parses the -start-block flag, a comma separated list of
+// chain=block pairs. A bare block number applies to every chain.
+func ParseStartBlocks(value string) (map[string]uint64, error) {
+	startBlocks := make(map[string]uint64)
+	if value == "" {
+		return startBlocks, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		chain, block, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			chain, block = "", chain
+		}
+		number, err := strconv.ParseUint(block, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start block %q: %w", entry, err)
+		}
+		startBlocks[chain] = number
+	}
+	return startBlocks, nil
+}
+
+func (t *Tracker) handlerTracking(ctx context.Context, chainConfig models.ChainConfig, startBlock uint64) error {
+	client, err := t.dial(ctx, chainConfig)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %v", err)
 	}
+	defer client.Close()
+	t.clients.Store(chainConfig.Chain, client)
+	defer t.clients.CompareAndDelete(chainConfig.Chain, client)
 
-	chainID, e := client.NetworkID(ctx)
+	chainID, e := client.ChainID(ctx)
 	if e != nil {
 		return fmt.Errorf("failed to get chain ID: %v", e)
 	}
-	blockNumber := big.NewInt(BlockNumber)
+	signer := types.LatestSignerForChainID(chainID)
+	err = t.loadWatchlist(ctx, chainConfig.Chain)
+	if err != nil {
+		return err
+	}
+	start, err := t.resolveStartBlock(ctx, client, chainConfig, startBlock)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%s] Tracking from block %d\n", chainConfig.Chain, start)
+
+	headCtx, stopHeads := context.WithCancel(ctx)
+	defer stopHeads()
+	heads := newHeadWatcher(chainConfig.Chain, client)
+	go heads.run(headCtx)
+
+	next := start
+	var head uint64
 	for {
-		block, err := client.BlockByNumber(ctx, blockNumber)
-		if err != nil && err.Error() == ethereum.NotFound.Error() {
-			fmt.Printf("Block %v not found yet", blockNumber)
-			time.Sleep(12 * time.Second)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if next > trackedHead(head, chainConfig) {
+			head, err = heads.Wait(ctx, head)
+			if err != nil {
+				return nil
+			}
+			t.heads.Store(chainConfig.Chain, head)
+			continue
+		}
+
+		// catch up in ranges, a single block at a time once at the head
+		to := trackedHead(head, chainConfig)
+		if to-next >= maxBlockRange {
+			to = next + maxBlockRange - 1
+		}
+		blocks, logs, err := t.fetchRange(ctx, client, chainConfig, next, to)
+		if errors.Is(err, ethereum.NotFound) || errors.Is(err, errRangeChanged) {
+			fmt.Printf("[%s] Blocks %d-%d not available yet: %v\n", chainConfig.Chain, next, to, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
 			continue
 		}
 		if err != nil {
-			fmt.Print("Failed to parse ABI:", err)
-			return fmt.Errorf("failed to parse ABI: %v", err)
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch blocks %d-%d: %v", next, to, err)
 		}
 
-		for _, tx := range block.Transactions() {
-			fmt.Printf("Block: %v\n", blockNumber)
-			// check native transfer
-			err = trackingNativeToken(tx, chainConfig, chainID)
+		// Finish the range even if shutdown is requested meanwhile.
+		rangeCtx, cancelRange := context.WithTimeout(context.WithoutCancel(ctx), rangeTimeout)
+		ancestor, reorged, err := t.findReorg(rangeCtx, client, chainConfig.Chain, blocks[0])
+		if err != nil {
+			cancelRange()
+			return fmt.Errorf("failed to check chain reorganization: %v", err)
+		}
+		if reorged {
+			err = t.rollbackReorg(rangeCtx, chainConfig, ancestor)
+			cancelRange()
 			if err != nil {
-				fmt.Printf("Failed to track native token: %v", err)
+				return fmt.Errorf("failed to rollback chain reorganization: %v", err)
 			}
-			// check Erc20 token transfer
-			err = trackingErc20Token(client, tx, chainConfig)
-			if err != nil {
-				fmt.Printf("Failed to track ERC20 token: %v", err)
+			next = ancestor + 1
+			continue
+		}
+
+		fmt.Printf("[%s] Blocks: %d-%d\n", chainConfig.Chain, next, to)
+		err = t.processRange(rangeCtx, client, chainConfig, signer, blocks, logs, head)
+		if err != nil {
+			cancelRange()
+			// the checkpoint is not moved, so the range is processed again
+			fmt.Printf("[%s] Failed to process blocks %d-%d, retrying in %v: %v\n", chainConfig.Chain, next, to, retryDelay, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
 			}
+			continue
+		}
+		err = t.store.SaveCheckpoint(rangeCtx, chainConfig.Chain, to)
+		cancelRange()
+		if err != nil {
+			fmt.Printf("Failed to save checkpoint: %v\n", err)
+		}
+		next = to + 1
+	}
+}
+
+// fetchRange downloads the blocks from..to and the matching Transfer logs,
+// grouped by block number. It fails with errRangeChanged when the chain was
+// reorganized while the range was being read.
+func (t *Tracker) fetchRange(ctx context.Context, client chainClient, chainConfig models.ChainConfig, from, to uint64) ([]*types.Block, map[uint64][]types.Log, error) {
+	blocks := make([]*types.Block, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(blocks) > 0 && block.ParentHash() != blocks[len(blocks)-1].Hash() {
+			return nil, nil, errRangeChanged
+		}
+		blocks = append(blocks, block)
+	}
+
+	transferLogs, err := t.filterTransferLogs(ctx, client, chainConfig, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	logs := make(map[uint64][]types.Log)
+	for _, log := range transferLogs {
+		if log.BlockNumber < from || log.BlockNumber > to {
+			// a misbehaving endpoint, the range is read again
+			return nil, nil, fmt.Errorf("log of block %d outside of blocks %d-%d", log.BlockNumber, from, to)
+		}
+		if log.BlockHash != blocks[log.BlockNumber-from].Hash() {
+			return nil, nil, errRangeChanged
+		}
+		logs[log.BlockNumber] = append(logs[log.BlockNumber], log)
+	}
+	return blocks, logs, nil
+}
+
+// processRange tracks the transfers of blocks, then settles the pending
+// transfers that head made deep enough. Processing a range again is safe, as
+// transfers already stored are neither stored nor announced twice.
+func (t *Tracker) processRange(ctx context.Context, client chainClient, chainConfig models.ChainConfig, signer types.Signer, blocks []*types.Block, logs map[uint64][]types.Log, head uint64) error {
+	for _, block := range blocks {
+		err := t.processBlock(ctx, client, chainConfig, signer, block, logs[block.NumberU64()])
+		if err != nil {
+			return fmt.Errorf("block %d: %w", block.NumberU64(), err)
+		}
+	}
+	if chainConfig.PendingConfirmations {
+		err := t.promotePending(ctx, chainConfig, head)
+		if err != nil {
+			return fmt.Errorf("failed to promote pending transfers: %w", err)
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) processBlock(ctx context.Context, client chainClient, chainConfig models.ChainConfig, signer types.Signer, block *types.Block, logs []types.Log) error {
+	receipts := newReceiptCache(client)
+	// check native transfer
+	for _, tx := range block.Transactions() {
+		err := t.trackingNativeToken(ctx, receipts, block, tx, chainConfig, signer)
+		if err != nil {
+			return fmt.Errorf("failed to track native token: %w", err)
+		}
+	}
+	// check Erc20 token transfer
+	for _, log := range logs {
+		err := t.trackingErc20Token(ctx, client, receipts, block, log, chainConfig)
+		if err != nil {
+			return fmt.Errorf("failed to track ERC20 token: %w", err)
+		}
+	}
+	err := t.recordBlock(ctx, chainConfig, block)
+	if err != nil {
+		return fmt.Errorf("failed to record block: %w", err)
+	}
+	return nil
+}
+
+// trackedHead is the highest block the tracker may process given the chain
+// head: the head itself in pending mode, otherwise the deepest confirmed block.
+func trackedHead(head uint64, chainConfig models.ChainConfig) uint64 {
+	if chainConfig.PendingConfirmations {
+		return head
+	}
+	if head < chainConfig.Confirmations {
+		return 0
+	}
+	return head - chainConfig.Confirmations
+}
+
+// promotePending confirms and announces the pending transfers that are now
+// deep enough, and invalidates the ones whose block was orphaned.
+func (t *Tracker) promotePending(ctx context.Context, chainConfig models.ChainConfig, head uint64) error {
+	if head < chainConfig.Confirmations {
+		return nil
+	}
+	_, err := t.store.PromotePending(ctx, chainConfig.Chain, head-chainConfig.Confirmations,
+		t.alertFunc(chainConfig, notify.EventTransfer))
+	return err
+}
+
+// resolveStartBlock picks the first block to scan: the explicit override if
+// set, otherwise the block after the stored checkpoint, unless the configured
+// start block is further ahead. A chain with neither starts at the head.
+func (t *Tracker) resolveStartBlock(ctx context.Context, client chainClient, chainConfig models.ChainConfig, override uint64) (uint64, error) {
+	if override > 0 {
+		return override, nil
+	}
+	checkpoint, found, err := t.store.GetCheckpoint(ctx, chainConfig.Chain)
+	if err != nil {
+		return 0, err
+	}
+	start := chainConfig.StartBlock
+	if found && checkpoint+1 > start {
+		start = checkpoint + 1
+	}
+	if start == 0 {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get head block: %v", err)
 		}
-		blockNumber.Add(blockNumber, big.NewInt(1))
+		start = trackedHead(head, chainConfig)
 	}
+	return start, nil
 }
 
-func trackingNativeToken(tx *types.Transaction, chainConfig models.ChainConfig, chainID *big.Int) error {
-	from, to := getTransactionAddresses(tx, chainID)
+func (t *Tracker) trackingNativeToken(ctx context.Context, receipts *receiptCache, block *types.Block, tx *types.Transaction, chainConfig models.ChainConfig, signer types.Signer) error {
+	from, to, err := getTransactionAddresses(tx, signer)
+	if err != nil {
+		fmt.Printf("[%s] Failed to get the sender of transaction %s: %v\n", chainConfig.Chain, tx.Hash().Hex(), err)
+	}
 
-	if !checkUserTracked(from, config.Chain) && !checkUserTracked(to, config.Chain) {
+	fromLabel, fromTracked := t.walletLabel(from, chainConfig.Chain)
+	toLabel, toTracked := t.walletLabel(to, chainConfig.Chain)
+	if !fromTracked && !toTracked {
 		return nil
 	}
 
@@ -126,144 +490,143 @@ func trackingNativeToken(tx *types.Transaction, chainConfig models.ChainConfig,
 		return nil
 	}
 
-	value := new(big.Float).Quo(new(big.Float).SetInt(tx.Value()), big.NewFloat(1e18))
 	trackingInfo := &models.TrackingInformation{
 		TransactionHash: tx.Hash().Hex(),
+		LogIndex:        models.NativeLogIndex,
 		Type:            TypeTokenNative,
 		From:            from,
 		To:              to,
-		Amount:          value.Text('f', -1),
-		Chain:           config.Chain,
-		Symbol:          config.ChainSymbol,
+		Amount:          models.NewBigInt(tx.Value()),
+		Decimals:        nativeDecimals,
+		Chain:           chainConfig.Chain,
+		Symbol:          chainConfig.ChainSymbol,
 		Token:           "",
+		FromLabel:       fromLabel,
+		ToLabel:         toLabel,
 	}
-
-	err := notifyAndSaveDB(trackingInfo, chainConfig)
+	err = setBlockDetails(ctx, trackingInfo, block, tx, receipts)
 	if err != nil {
 		return err
 	}
-	return nil
+	return t.notifyAndSaveDB(ctx, trackingInfo, chainConfig)
 }
 
-func trackingErc20Token(client *ethclient.Client, tx *types.Transaction, chainConfig models.ChainConfig) error {
-	receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+func (t *Tracker) trackingErc20Token(ctx context.Context, client chainClient, receipts *receiptCache, block *types.Block, log types.Log, chainConfig models.ChainConfig) error {
+	tokenAddress := strings.ToLower(log.Address.Hex())
+	if !t.watchlist.IsToken(chainConfig.Chain, tokenAddress) {
+		return nil
+	}
+	// ERC-721 transfers share the topic of ERC-20 ones but index the token
+	// ID, so they do not decode and are skipped like any other stray log
+	transfer, err := transferFilterer.ParseTransfer(log)
 	if err != nil {
-		fmt.Printf("failed to get transaction receipt: %v", err)
-		return err
+		fmt.Printf("[%s] Skipped log %d of transaction %s, not an ERC-20 transfer: %v\n", chainConfig.Chain, log.Index, log.TxHash.Hex(), err)
+		return nil
+	}
+	fromAddr, toAddr, amount, err := t.checkTransferLog(transfer, chainConfig.Chain)
+	if err != nil {
+		return nil
 	}
 
-	for _, log := range receipt.Logs {
-		tokenFilterer, err := token.NewStoreFilterer(log.Address, client)
-		if err != nil {
-			fmt.Printf("failed to create token filterer: %v", err)
-			continue
-		}
-		transfer, err := tokenFilterer.ParseTransfer(*log)
-		if err != nil {
-			fmt.Printf(err.Error())
-			continue
-		}
-		tokenAddress := strings.ToLower(log.Address.Hex())
-		_, ok := mapListTracking[chainConfig.Chain].MapListTokens[tokenAddress]
-		if !ok {
-			continue
-		}
-		fromAddr, toAddr, amount, err := checkTransferLog(transfer, chainConfig.Chain)
-		if err != nil {
-			fmt.Printf("failed to check transfer log: %v", err)
-			continue
-		}
+	decimals, symbol, err := t.tokenMetadata(ctx, client, chainConfig, log.Address)
+	if errors.Is(err, tokenmeta.ErrNotToken) {
+		fmt.Printf("[%s] Skipped transfer %s of token %s: %v\n", chainConfig.Chain, log.TxHash.Hex(), tokenAddress, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	trackingInfo := models.TrackingInformation{
+		TransactionHash: log.TxHash.Hex(),
+		LogIndex:        int(log.Index),
+		Type:            TypeTokenERC20,
+		From:            fromAddr,
+		To:              toAddr,
+		Amount:          models.NewBigInt(amount),
+		Decimals:        decimals,
+		Chain:           chainConfig.Chain,
+		Symbol:          symbol,
+		Token:           tokenAddress,
+		FromLabel:       t.walletLabelOf(fromAddr, chainConfig.Chain),
+		ToLabel:         t.walletLabelOf(toAddr, chainConfig.Chain),
+	}
+	tx := block.Transaction(log.TxHash)
+	if tx == nil {
+		return fmt.Errorf("transaction %s not found in block %d", log.TxHash.Hex(), block.NumberU64())
+	}
+	err = setBlockDetails(ctx, &trackingInfo, block, tx, receipts)
+	if err != nil {
+		return err
+	}
+	return t.notifyAndSaveDB(ctx, &trackingInfo, chainConfig)
+}
 
-		tokenContract, err := token.NewStore(log.Address, client)
-		if err != nil {
-			fmt.Printf("failed to create token contract: %v", err)
-			continue
-		}
-		tokenSymbol, err := tokenContract.Symbol(nil)
-		if err != nil {
-			fmt.Printf("failed to get token symbol: %v", err)
-			continue
-		}
-		decimals := uint8(18)
-		tokenConfig, ok := chainConfig.TrackingTokensConfig[tokenAddress]
-		if ok {
-			decimals = tokenConfig.Decimals
-		}
-		amountTransfer := new(big.Float).SetInt(amount)
-		amountTransfer = new(big.Float).Quo(amountTransfer, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
-		trackingInfo := models.TrackingInformation{
-			TransactionHash: tx.Hash().Hex(),
-			Type:            TypeTokenERC20,
-			From:            fromAddr,
-			To:              toAddr,
-			Amount:          amountTransfer.Text('f', -1),
-			Chain:           chainConfig.Chain,
-			Symbol:          tokenSymbol,
-			Token:           tokenAddress,
-		}
-		err = notifyAndSaveDB(&trackingInfo, chainConfig)
-		if err != nil {
-			fmt.Printf("failed to save tracking info: %v", err)
+// tokenMetadata returns the decimals and symbol of a token. A token of the
+// config file with a symbol is taken as configured; otherwise its metadata is
+// resolved, with the configured decimals correcting the reported ones, and
+// the config is used alone when resolving fails.
+func (t *Tracker) tokenMetadata(ctx context.Context, client chainClient, chainConfig models.ChainConfig, address common.Address) (uint8, string, error) {
+	tokenConfig, configured := chainConfig.TrackingTokensConfig[strings.ToLower(address.Hex())]
+	if configured && tokenConfig.Symbol != "" {
+		return tokenConfig.Decimals, tokenConfig.Symbol, nil
+	}
+	metadata, err := t.tokens.Resolve(ctx, chainConfig.Chain, client, address)
+	if err != nil {
+		if configured {
+			fmt.Printf("[%s] Failed to get metadata of token %s, using the config: %v\n", chainConfig.Chain, address.Hex(), err)
+			return tokenConfig.Decimals, tokenConfig.Symbol, nil
 		}
+		return 0, "", fmt.Errorf("failed to get token metadata: %w", err)
 	}
-	return nil
+	if configured {
+		return tokenConfig.Decimals, metadata.Symbol, nil
+	}
+	return metadata.Decimals, metadata.Symbol, nil
 }
 
-func checkTransferLog(transfer *token.StoreTransfer, chain string) (string, string, *big.Int, error) {
+func (t *Tracker) checkTransferLog(transfer *token.StoreTransfer, chain string) (string, string, *big.Int, error) {
 	fromAddress := strings.ToLower(transfer.From.Hex())
 	toAddress := strings.ToLower(transfer.To.Hex())
 
-	if !checkUserTracked(fromAddress, chain) && !checkUserTracked(toAddress, chain) {
+	if !t.checkUserTracked(fromAddress, chain) && !t.checkUserTracked(toAddress, chain) {
 		return "", "", nil, fmt.Errorf("not tracking this user")
 	}
 	return fromAddress, toAddress, transfer.Value, nil
 }
 
-func notifyAndSaveDB(trackingInfo *models.TrackingInformation, chainConfig models.ChainConfig) error {
-	tokenSymbol := ""
-	switch trackingInfo.Type {
-	case TypeTokenNative:
-		tokenSymbol = chainConfig.ChainSymbol
-	case TypeTokenERC20:
-		tokenTrackingConfig, ok := chainConfig.TrackingTokensConfig[trackingInfo.Token]
-		if ok {
-			tokenSymbol = tokenTrackingConfig.Symbol
-		}
-	default:
+func (t *Tracker) notifyAndSaveDB(ctx context.Context, trackingInfo *models.TrackingInformation, chainConfig models.ChainConfig) error {
+	trackingInfo.Status = models.StatusConfirmed
+	if chainConfig.PendingConfirmations && chainConfig.Confirmations > 0 {
+		trackingInfo.Status = models.StatusPending
 	}
 
-	err := api.SaveDB(trackingInfo)
-	if err != nil {
-		fmt.Printf("failed to save tracking info: %v", err)
+	// Every transfer is stored, the alert rules only decide whether and to
+	// whom it is announced. Pending transfers are announced once they are
+	// confirmed.
+	var alert store.AlertFunc
+	if trackingInfo.Status != models.StatusPending {
+		alert = t.alertFunc(chainConfig, notify.EventTransfer)
 	}
-
-	message := fmt.Sprintf(`Chain: %s
-			Transaction: %s
-			Transfering %s %s
-			From %s to %s`, trackingInfo.Chain,
-		trackingInfo.TransactionHash, trackingInfo.Amount, tokenSymbol, trackingInfo.From, trackingInfo.To)
-
-	err = SendMessage(message)
+	// an event seen before, e.g. when a range is processed again, is not
+	// announced twice
+	_, err := t.store.SaveTracking(ctx, trackingInfo, alert)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to save tracking info: %v", err)
 	}
 	return nil
 }
-func getTransactionAddresses(tx *types.Transaction, chainID *big.Int) (string, string) {
+
+// getTransactionAddresses returns the sender and the recipient of tx. The
+// recipient is returned even if the sender cannot be recovered.
+func getTransactionAddresses(tx *types.Transaction, signer types.Signer) (string, string, error) {
 	from, to := "", ""
-	sender, err := types.Sender(types.NewLondonSigner(chainID), tx)
+	sender, err := types.Sender(signer, tx)
 	if err == nil {
 		from = sender.Hex()
 	}
-	to = tx.To().Hex()
-	return strings.ToLower(from), strings.ToLower(to)
-}
-
-func checkUserTracked(address string, chain string) bool {
-	address = strings.ToLower(address)
-	trackedUser := mapListTracking[chain].UsersTracking
-	if trackedUser == "" {
-		return false
+	// contract creations have no recipient
+	if tx.To() != nil {
+		to = tx.To().Hex()
 	}
-	return address == strings.ToLower(trackedUser)
+	return strings.ToLower(from), strings.ToLower(to), err
 }
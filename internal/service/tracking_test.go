@@ -0,0 +1,133 @@
+package service
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/tokenmeta"
+	"Intermediate_web3/internal/watchlist"
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const (
+	testToken  = "0x00000000000000000000000000000000000000cc"
+	testWallet = "0x00000000000000000000000000000000000000aa"
+)
+
+func newTestTracker(t *testing.T, trackingStore store.TrackingStore) (*Tracker, models.ChainConfig) {
+	chainConfig := models.ChainConfig{
+		Chain:              "eth",
+		Wallets:            []models.WalletConfig{{Address: testWallet}},
+		ListTokensTracking: []string{testToken},
+		TrackingTokensConfig: map[string]models.TokenConfig{
+			testToken: {Symbol: "TKN", Decimals: 6},
+		},
+	}
+	tracker := NewTracker(&models.Config{Chains: []models.ChainConfig{chainConfig}}, trackingStore, watchlist.New(), nil, nil, nil)
+	return tracker, chainConfig
+}
+
+func TestProcessBlockSkipsERC721Transfers(t *testing.T) {
+	trackingStore := store.NewMemory()
+	tracker, chainConfig := newTestTracker(t, trackingStore)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	// Transfer(address,address,uint256) of ERC-721, with the token ID as a
+	// fourth topic and no data
+	nftTransfer := types.Log{
+		Address: common.HexToAddress(testToken),
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
+			common.HexToHash(testWallet),
+			common.HexToHash("0xbb"),
+			common.BigToHash(big.NewInt(7)),
+		},
+		BlockNumber: 10,
+		TxHash:      common.HexToHash("0x1"),
+	}
+
+	err := tracker.processBlock(context.Background(), fakeChain{}, chainConfig, types.LatestSignerForChainID(big.NewInt(1)), block, []types.Log{nftTransfer})
+	if err != nil {
+		t.Fatalf("processBlock = %v, want the log skipped", err)
+	}
+	if count, _ := trackingStore.CountTracking(context.Background(), store.TrackingFilter{}); count != 0 {
+		t.Errorf("stored %d transfers, want none", count)
+	}
+	if _, found, _ := trackingStore.GetBlock(context.Background(), "eth", 10); !found {
+		t.Error("block 10 is not recorded")
+	}
+}
+
+// revertError is the error of a node for a reverted call.
+type revertError struct{}
+
+func (revertError) Error() string  { return "execution reverted" }
+func (revertError) ErrorCode() int { return 3 }
+
+// revertingChain is a chain on which every contract call reverts.
+type revertingChain struct {
+	fakeChain
+}
+
+func (revertingChain) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, revertError{}
+}
+
+func TestProcessBlockSkipsTransfersOfNonTokens(t *testing.T) {
+	trackingStore := store.NewMemory()
+	tracker, chainConfig := newTestTracker(t, trackingStore)
+	tracker.tokens = tokenmeta.NewService(trackingStore)
+	// a token added at runtime, whose metadata is resolved on the chain
+	const contract = "0x00000000000000000000000000000000000000dd"
+	tracker.watchlist.SetToken("eth", contract, "")
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	transfer := types.Log{
+		Address: common.HexToAddress(contract),
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
+			common.HexToHash(testWallet),
+			common.HexToHash("0xbb"),
+		},
+		Data:        common.BigToHash(big.NewInt(7)).Bytes(),
+		BlockNumber: 10,
+		TxHash:      common.HexToHash("0x1"),
+	}
+
+	err := tracker.processBlock(context.Background(), revertingChain{}, chainConfig, types.LatestSignerForChainID(big.NewInt(1)), block, []types.Log{transfer})
+	if err != nil {
+		t.Fatalf("processBlock = %v, want the transfer skipped", err)
+	}
+	if _, found, _ := trackingStore.GetBlock(context.Background(), "eth", 10); !found {
+		t.Error("block 10 is not recorded")
+	}
+}
+
+func TestGetTransactionAddressesOfBlobTransactions(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(10))
+	recipient := common.HexToAddress(testWallet)
+	for _, data := range []types.TxData{
+		&types.LegacyTx{To: &recipient, Value: big.NewInt(1), Gas: 21000, GasPrice: big.NewInt(1)},
+		&types.DynamicFeeTx{ChainID: big.NewInt(10), To: &recipient, Value: big.NewInt(1), Gas: 21000},
+		&types.BlobTx{ChainID: uint256.NewInt(10), To: recipient, Value: uint256.NewInt(1), Gas: 21000, BlobHashes: []common.Hash{{1}}},
+	} {
+		tx, err := types.SignNewTx(key, signer, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, to, err := getTransactionAddresses(tx, signer)
+		want := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+		if err != nil || from != want || to != testWallet {
+			t.Errorf("addresses of a type %d transaction = %s, %s, %v, want %s, %s", tx.Type(), from, to, err, want, testWallet)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+)
+
+// loadWatchlist loads the wallets and tokens of a chain stored in the
+// database into the watchlist, next to the ones of the config file.
+func (t *Tracker) loadWatchlist(ctx context.Context, chain string) error {
+	wallets, err := t.store.GetWallets(ctx, chain)
+	if err != nil {
+		return err
+	}
+	trackedTokens, err := t.store.GetTrackedTokens(ctx, chain)
+	if err != nil {
+		return err
+	}
+	t.watchlist.SetStored(chain, wallets, trackedTokens)
+	return nil
+}
+
+// walletLabel returns the label of a watched wallet, and whether the address
+// is watched at all.
+func (t *Tracker) walletLabel(address string, chain string) (string, bool) {
+	return t.watchlist.WalletLabel(chain, address)
+}
+
+func (t *Tracker) walletLabelOf(address string, chain string) string {
+	label, _ := t.walletLabel(address, chain)
+	return label
+}
+
+func (t *Tracker) checkUserTracked(address string, chain string) bool {
+	_, ok := t.walletLabel(address, chain)
+	return ok
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"Intermediate_web3/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Cursor is the position of a transfer in a sort order. Listing after a
+// cursor is stable while new transfers are stored, unlike an offset.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"i"`
+}
+
+// CursorOf returns the cursor pointing to trackingInfo in the sort order of
+// filter.
+func CursorOf(trackingInfo models.TrackingInformation, filter TrackingFilter) Cursor {
+	sort := filter.Sort
+	if sort == "" {
+		sort = SortID
+	}
+	return Cursor{
+		Sort:  sort,
+		Desc:  filter.Desc,
+		Value: sortValue(trackingInfo, sort),
+		ID:    trackingInfo.ID,
+	}
+}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode.
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if !ValidSort(cursor.Sort) {
+		return Cursor{}, fmt.Errorf("invalid cursor: unknown sort %q", cursor.Sort)
+	}
+	switch cursor.Sort {
+	case SortBlockNumber:
+		_, err = strconv.ParseUint(cursor.Value, 10, 64)
+	case SortBlockTime:
+		_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	case SortAmount:
+		_, err = models.ParseBigInt(cursor.Value)
+	}
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+func sortValue(trackingInfo models.TrackingInformation, sort string) string {
+	switch sort {
+	case SortBlockNumber:
+		return strconv.FormatUint(trackingInfo.BlockNumber, 10)
+	case SortBlockTime:
+		return trackingInfo.BlockTime.UTC().Format(time.RFC3339Nano)
+	case SortAmount:
+		return trackingInfo.Amount.String()
+	}
+	return ""
+}
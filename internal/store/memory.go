@@ -0,0 +1,577 @@
+package store
+
+import (
+	"Intermediate_web3/internal/models"
+	"cmp"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a TrackingStore kept in memory, for tests and for running without
+// a database. It is safe for concurrent use.
+type Memory struct {
+	mu            sync.RWMutex
+	nextID        int
+	tracking      []models.TrackingInformation
+	checkpoints   map[string]models.Checkpoint
+	blocks        map[string]map[uint64]models.TrackedBlock
+	wallets       []models.Wallet
+	trackedTokens []models.TrackedToken
+	nextListID    int
+	tokens        map[string]models.Token
+	outbox        []models.OutboxMessage
+	nextOutboxID  int64
+	alertRules    []models.AlertRule
+	nextRuleID    int64
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		nextID:       1,
+		nextListID:   1,
+		nextOutboxID: 1,
+		nextRuleID:   1,
+		checkpoints:  make(map[string]models.Checkpoint),
+		blocks:       make(map[string]map[uint64]models.TrackedBlock),
+		tokens:       make(map[string]models.Token),
+	}
+}
+
+func (s *Memory) SaveTracking(ctx context.Context, trackingInfo *models.TrackingInformation, alert AlertFunc) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if trackingInfo.Status == "" {
+		trackingInfo.Status = models.StatusConfirmed
+	}
+	for i, stored := range s.tracking {
+		if stored.Chain != trackingInfo.Chain ||
+			stored.TransactionHash != trackingInfo.TransactionHash ||
+			stored.LogIndex != trackingInfo.LogIndex {
+			continue
+		}
+		if stored.Status != models.StatusInvalidated {
+			return false, nil
+		}
+		trackingInfo.ID = stored.ID
+		s.tracking[i] = *trackingInfo
+		s.queueAlerts(alert, []models.TrackingInformation{*trackingInfo})
+		return true, nil
+	}
+
+	trackingInfo.ID = s.nextID
+	s.nextID++
+	s.tracking = append(s.tracking, *trackingInfo)
+	s.queueAlerts(alert, []models.TrackingInformation{*trackingInfo})
+	return true, nil
+}
+
+// queueAlerts adds the alerts of alert for each transfer to the outbox.
+func (s *Memory) queueAlerts(alert AlertFunc, tracking []models.TrackingInformation) {
+	if alert == nil {
+		return
+	}
+	for i := range tracking {
+		for _, message := range alert(&tracking[i]) {
+			message.ID = s.nextOutboxID
+			s.nextOutboxID++
+			if message.CreatedAt.IsZero() {
+				message.CreatedAt = time.Now()
+			}
+			s.outbox = append(s.outbox, message)
+		}
+	}
+}
+
+func (s *Memory) QueryTracking(ctx context.Context, filter TrackingFilter) ([]models.TrackingInformation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tracking []models.TrackingInformation
+	for _, trackingInfo := range s.tracking {
+		if !matchTrackingFilter(trackingInfo, filter) {
+			continue
+		}
+		if filter.After != nil && compareSortKey(trackingInfo, *filter.After, filter.Desc) <= 0 {
+			continue
+		}
+		tracking = append(tracking, trackingInfo)
+	}
+	sort.Slice(tracking, func(i, j int) bool {
+		return compareSortKey(tracking[j], CursorOf(tracking[i], filter), filter.Desc) > 0
+	})
+	if filter.Offset >= len(tracking) {
+		return nil, nil
+	}
+	tracking = tracking[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(tracking) {
+		tracking = tracking[:filter.Limit]
+	}
+	return tracking, nil
+}
+
+func (s *Memory) CountTracking(ctx context.Context, filter TrackingFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, trackingInfo := range s.tracking {
+		if matchTrackingFilter(trackingInfo, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// compareSortKey compares the position of trackingInfo with the cursor in the
+// sort order of the cursor, descending if desc.
+func compareSortKey(trackingInfo models.TrackingInformation, cursor Cursor, desc bool) int {
+	result := 0
+	switch cursor.Sort {
+	case SortBlockNumber:
+		number, _ := strconv.ParseUint(cursor.Value, 10, 64)
+		result = cmp.Compare(trackingInfo.BlockNumber, number)
+	case SortBlockTime:
+		blockTime, _ := time.Parse(time.RFC3339Nano, cursor.Value)
+		result = trackingInfo.BlockTime.Compare(blockTime)
+	case SortAmount:
+		amount, _ := models.ParseBigInt(cursor.Value)
+		result = trackingInfo.Amount.Compare(amount)
+	}
+	if result == 0 {
+		result = cmp.Compare(trackingInfo.ID, cursor.ID)
+	}
+	if desc {
+		return -result
+	}
+	return result
+}
+
+func matchTrackingFilter(trackingInfo models.TrackingInformation, filter TrackingFilter) bool {
+	if filter.Chain != "" && trackingInfo.Chain != filter.Chain {
+		return false
+	}
+	if filter.Type != "" && !strings.EqualFold(trackingInfo.Type, filter.Type) {
+		return false
+	}
+	if filter.Token != "" && trackingInfo.Token != strings.ToLower(filter.Token) {
+		return false
+	}
+	if filter.Symbol != "" && !strings.EqualFold(trackingInfo.Symbol, filter.Symbol) {
+		return false
+	}
+	if filter.From != "" && trackingInfo.From != strings.ToLower(filter.From) {
+		return false
+	}
+	if filter.To != "" && trackingInfo.To != strings.ToLower(filter.To) {
+		return false
+	}
+	if filter.Address != "" {
+		address := strings.ToLower(filter.Address)
+		if trackingInfo.From != address && trackingInfo.To != address {
+			return false
+		}
+	}
+	if filter.TransactionHash != "" && trackingInfo.TransactionHash != strings.ToLower(filter.TransactionHash) {
+		return false
+	}
+	if filter.MinAmount != nil && trackingInfo.Amount.Compare(*filter.MinAmount) < 0 {
+		return false
+	}
+	if filter.MaxAmount != nil && trackingInfo.Amount.Compare(*filter.MaxAmount) > 0 {
+		return false
+	}
+	if filter.FromBlock != nil && trackingInfo.BlockNumber < *filter.FromBlock {
+		return false
+	}
+	if filter.ToBlock != nil && trackingInfo.BlockNumber > *filter.ToBlock {
+		return false
+	}
+	if filter.FromTime != nil && trackingInfo.BlockTime.Before(*filter.FromTime) {
+		return false
+	}
+	if filter.ToTime != nil && !trackingInfo.BlockTime.Before(*filter.ToTime) {
+		return false
+	}
+	return true
+}
+
+func (s *Memory) DeleteTracking(ctx context.Context, transactionHash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+	s.tracking = s.filterTracking(func(trackingInfo models.TrackingInformation) bool {
+		if strings.EqualFold(trackingInfo.TransactionHash, transactionHash) {
+			removed++
+			return false
+		}
+		return true
+	})
+	return removed, nil
+}
+
+// filterTracking returns the stored transfers for which keep is true.
+func (s *Memory) filterTracking(keep func(trackingInfo models.TrackingInformation) bool) []models.TrackingInformation {
+	kept := s.tracking[:0]
+	for _, trackingInfo := range s.tracking {
+		if keep(trackingInfo) {
+			kept = append(kept, trackingInfo)
+		}
+	}
+	return kept
+}
+
+func (s *Memory) GetCheckpoint(ctx context.Context, chain string) (uint64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checkpoint, ok := s.checkpoints[chain]
+	return checkpoint.BlockNumber, ok, nil
+}
+
+func (s *Memory) SaveCheckpoint(ctx context.Context, chain string, blockNumber uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[chain] = models.Checkpoint{
+		Chain:       chain,
+		BlockNumber: blockNumber,
+		UpdatedAt:   time.Now(),
+	}
+	return nil
+}
+
+func (s *Memory) GetBlock(ctx context.Context, chain string, blockNumber uint64) (*models.TrackedBlock, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	block, ok := s.blocks[chain][blockNumber]
+	if !ok {
+		return nil, false, nil
+	}
+	return &block, true, nil
+}
+
+func (s *Memory) SaveBlock(ctx context.Context, block *models.TrackedBlock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.blocks[block.Chain] == nil {
+		s.blocks[block.Chain] = make(map[uint64]models.TrackedBlock)
+	}
+	s.blocks[block.Chain][block.BlockNumber] = *block
+	return nil
+}
+
+func (s *Memory) PruneBlocks(ctx context.Context, chain string, blockNumber uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for number := range s.blocks[chain] {
+		if number < blockNumber {
+			delete(s.blocks[chain], number)
+		}
+	}
+	return nil
+}
+
+func (s *Memory) RollbackBlocks(ctx context.Context, chain string, blockNumber uint64, alert AlertFunc) ([]models.TrackingInformation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []models.TrackingInformation
+	s.tracking = s.filterTracking(func(trackingInfo models.TrackingInformation) bool {
+		if trackingInfo.Chain != chain || trackingInfo.BlockNumber <= blockNumber {
+			return true
+		}
+		if trackingInfo.Status == models.StatusConfirmed {
+			removed = append(removed, trackingInfo)
+			return false
+		}
+		return true
+	})
+	s.queueAlerts(alert, removed)
+	for i := range s.tracking {
+		trackingInfo := &s.tracking[i]
+		if trackingInfo.Chain == chain && trackingInfo.BlockNumber > blockNumber && trackingInfo.Status == models.StatusPending {
+			trackingInfo.Status = models.StatusInvalidated
+		}
+	}
+
+	for number := range s.blocks[chain] {
+		if number > blockNumber {
+			delete(s.blocks[chain], number)
+		}
+	}
+	if checkpoint, ok := s.checkpoints[chain]; ok {
+		checkpoint.BlockNumber = blockNumber
+		checkpoint.UpdatedAt = time.Now()
+		s.checkpoints[chain] = checkpoint
+	}
+	return removed, nil
+}
+
+func (s *Memory) PromotePending(ctx context.Context, chain string, blockNumber uint64, alert AlertFunc) ([]models.TrackingInformation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var confirmed []models.TrackingInformation
+	for i := range s.tracking {
+		trackingInfo := &s.tracking[i]
+		if trackingInfo.Chain != chain || trackingInfo.BlockNumber > blockNumber || trackingInfo.Status != models.StatusPending {
+			continue
+		}
+		block, ok := s.blocks[chain][trackingInfo.BlockNumber]
+		if !ok || block.BlockHash != trackingInfo.BlockHash {
+			trackingInfo.Status = models.StatusInvalidated
+			continue
+		}
+		trackingInfo.Status = models.StatusConfirmed
+		confirmed = append(confirmed, *trackingInfo)
+	}
+	s.queueAlerts(alert, confirmed)
+	return confirmed, nil
+}
+
+func (s *Memory) GetWallets(ctx context.Context, chain string) ([]models.Wallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var wallets []models.Wallet
+	for _, wallet := range s.wallets {
+		if chain == "" || wallet.Chain == chain {
+			wallets = append(wallets, wallet)
+		}
+	}
+	return wallets, nil
+}
+
+func (s *Memory) SaveWallet(ctx context.Context, wallet *models.Wallet) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.wallets {
+		if stored.Chain == wallet.Chain && stored.Address == wallet.Address {
+			s.wallets[i].Label = wallet.Label
+			*wallet = s.wallets[i]
+			return false, nil
+		}
+	}
+	wallet.ID = s.nextListID
+	wallet.CreatedAt = time.Now()
+	s.nextListID++
+	s.wallets = append(s.wallets, *wallet)
+	return true, nil
+}
+
+func (s *Memory) DeleteWallet(ctx context.Context, chain string, address string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.wallets {
+		if stored.Chain == chain && stored.Address == address {
+			s.wallets = append(s.wallets[:i], s.wallets[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Memory) GetTrackedTokens(ctx context.Context, chain string) ([]models.TrackedToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var trackedTokens []models.TrackedToken
+	for _, trackedToken := range s.trackedTokens {
+		if chain == "" || trackedToken.Chain == chain {
+			trackedTokens = append(trackedTokens, trackedToken)
+		}
+	}
+	return trackedTokens, nil
+}
+
+func (s *Memory) SaveTrackedToken(ctx context.Context, trackedToken *models.TrackedToken) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.trackedTokens {
+		if stored.Chain == trackedToken.Chain && stored.Address == trackedToken.Address {
+			s.trackedTokens[i].Label = trackedToken.Label
+			*trackedToken = s.trackedTokens[i]
+			return false, nil
+		}
+	}
+	trackedToken.ID = s.nextListID
+	trackedToken.CreatedAt = time.Now()
+	s.nextListID++
+	s.trackedTokens = append(s.trackedTokens, *trackedToken)
+	return true, nil
+}
+
+func (s *Memory) DeleteTrackedToken(ctx context.Context, chain string, address string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.trackedTokens {
+		if stored.Chain == chain && stored.Address == address {
+			s.trackedTokens = append(s.trackedTokens[:i], s.trackedTokens[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Memory) GetToken(ctx context.Context, chain string, address string) (*models.Token, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[chain+"/"+address]
+	if !ok {
+		return nil, false, nil
+	}
+	return &token, true, nil
+}
+
+func (s *Memory) SaveToken(ctx context.Context, token *models.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.tokens[token.Chain+"/"+token.Address]; ok {
+		token.CreatedAt = stored.CreatedAt
+	} else {
+		token.CreatedAt = time.Now()
+	}
+	s.tokens[token.Chain+"/"+token.Address] = *token
+	return nil
+}
+
+func (s *Memory) GetDueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []models.OutboxMessage
+	for _, message := range s.outbox {
+		if limit > 0 && len(messages) >= limit {
+			break
+		}
+		if message.Status == models.OutboxPending && !message.NextAttemptAt.After(now) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (s *Memory) UpdateOutbox(ctx context.Context, message *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.outbox {
+		if stored.ID == message.ID {
+			s.outbox[i].Status = message.Status
+			s.outbox[i].Attempts = message.Attempts
+			s.outbox[i].NextAttemptAt = message.NextAttemptAt
+			s.outbox[i].LastError = message.LastError
+			s.outbox[i].DeliveredAt = message.DeliveredAt
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Memory) QueryOutbox(ctx context.Context, status string, limit int, offset int) ([]models.OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []models.OutboxMessage
+	for i := len(s.outbox) - 1; i >= 0; i-- {
+		if status == "" || s.outbox[i].Status == status {
+			messages = append(messages, s.outbox[i])
+		}
+	}
+	if offset >= len(messages) {
+		return nil, nil
+	}
+	messages = messages[offset:]
+	if limit > 0 && limit < len(messages) {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (s *Memory) CountOutbox(ctx context.Context, status string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, message := range s.outbox {
+		if status == "" || message.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Memory) RetryOutbox(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.outbox {
+		if s.outbox[i].ID == id {
+			s.outbox[i].Status = models.OutboxPending
+			s.outbox[i].Attempts = 0
+			s.outbox[i].NextAttemptAt = time.Now()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Memory) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.AlertRule(nil), s.alertRules...), nil
+}
+
+func (s *Memory) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule.ID = s.nextRuleID
+	rule.CreatedAt = time.Now()
+	s.nextRuleID++
+	s.alertRules = append(s.alertRules, *rule)
+	return nil
+}
+
+func (s *Memory) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.alertRules {
+		if stored.ID == rule.ID {
+			rule.CreatedAt = stored.CreatedAt
+			s.alertRules[i] = *rule
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Memory) DeleteAlertRule(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.alertRules {
+		if stored.ID == id {
+			s.alertRules = append(s.alertRules[:i], s.alertRules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func transfer(chain, hash string, logIndex int, blockNumber uint64, amount int64) *models.TrackingInformation {
+	return &models.TrackingInformation{
+		Chain:           chain,
+		TransactionHash: hash,
+		LogIndex:        logIndex,
+		BlockNumber:     blockNumber,
+		BlockHash:       "block" + hash,
+		Amount:          models.NewBigInt(big.NewInt(amount)),
+		From:            "0xfrom",
+		To:              "0xto",
+	}
+}
+
+// alertOnce queues one outbox message per transfer.
+func alertOnce(trackingInfo *models.TrackingInformation) []models.OutboxMessage {
+	return []models.OutboxMessage{{
+		Notifier:      "test",
+		Subject:       trackingInfo.TransactionHash,
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}}
+}
+
+func TestMemorySaveTrackingDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+
+	saved, err := s.SaveTracking(ctx, transfer("eth", "0xa", 0, 1, 10), alertOnce)
+	if err != nil || !saved {
+		t.Fatalf("first save = %v, %v, want true", saved, err)
+	}
+	saved, err = s.SaveTracking(ctx, transfer("eth", "0xa", 0, 1, 10), alertOnce)
+	if err != nil || saved {
+		t.Fatalf("second save = %v, %v, want false", saved, err)
+	}
+	saved, err = s.SaveTracking(ctx, transfer("eth", "0xa", 1, 1, 10), alertOnce)
+	if err != nil || !saved {
+		t.Fatalf("save of another log = %v, %v, want true", saved, err)
+	}
+
+	count, _ := s.CountTracking(ctx, TrackingFilter{})
+	if count != 2 {
+		t.Errorf("stored %d transfers, want 2", count)
+	}
+	outbox, _ := s.CountOutbox(ctx, models.OutboxPending)
+	if outbox != 2 {
+		t.Errorf("queued %d alerts, want 2", outbox)
+	}
+}
+
+func TestMemoryQueryTrackingPages(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	for i, amount := range []int64{30, 10, 50, 20, 40} {
+		_, err := s.SaveTracking(ctx, transfer("eth", "0x"+string(rune('a'+i)), 0, uint64(i), amount), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = s.SaveTracking(ctx, transfer("bsc", "0xz", 0, 9, 99), nil)
+
+	filter := TrackingFilter{Chain: "eth", Sort: SortAmount, Desc: true, Limit: 2}
+	var amounts []string
+	for {
+		page, err := s.QueryTracking(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, trackingInfo := range page {
+			amounts = append(amounts, trackingInfo.Amount.String())
+		}
+		if len(page) < filter.Limit {
+			break
+		}
+		cursor := CursorOf(page[len(page)-1], filter)
+		filter.After = &cursor
+	}
+	want := []string{"50", "40", "30", "20", "10"}
+	if len(amounts) != len(want) {
+		t.Fatalf("amounts = %v, want %v", amounts, want)
+	}
+	for i := range want {
+		if amounts[i] != want[i] {
+			t.Fatalf("amounts = %v, want %v", amounts, want)
+		}
+	}
+}
+
+func TestMemoryDeleteTrackingIgnoresCase(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	_, _ = s.SaveTracking(ctx, transfer("eth", "0xabc", 0, 1, 1), nil)
+	_, _ = s.SaveTracking(ctx, transfer("eth", "0xabc", 1, 1, 1), nil)
+
+	removed, err := s.DeleteTracking(ctx, "0xABC")
+	if err != nil || removed != 2 {
+		t.Fatalf("DeleteTracking = %d, %v, want 2", removed, err)
+	}
+	removed, _ = s.DeleteTracking(ctx, "0xabc")
+	if removed != 0 {
+		t.Errorf("second DeleteTracking = %d, want 0", removed)
+	}
+}
+
+func TestMemoryRollbackBlocks(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	for number := uint64(1); number <= 3; number++ {
+		_ = s.SaveBlock(ctx, &models.TrackedBlock{Chain: "eth", BlockNumber: number})
+	}
+	_ = s.SaveCheckpoint(ctx, "eth", 3)
+	_, _ = s.SaveTracking(ctx, transfer("eth", "0x1", 0, 1, 1), nil)
+	_, _ = s.SaveTracking(ctx, transfer("eth", "0x2", 0, 2, 1), nil)
+	pending := transfer("eth", "0x3", 0, 3, 1)
+	pending.Status = models.StatusPending
+	_, _ = s.SaveTracking(ctx, pending, nil)
+	_, _ = s.SaveTracking(ctx, transfer("bsc", "0x4", 0, 2, 1), nil)
+
+	removed, err := s.RollbackBlocks(ctx, "eth", 1, alertOnce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].TransactionHash != "0x2" {
+		t.Errorf("removed %v, want the transfer of 0x2", removed)
+	}
+	if outbox, _ := s.CountOutbox(ctx, ""); outbox != 1 {
+		t.Errorf("queued %d corrections, want 1", outbox)
+	}
+	invalidated, _ := s.QueryTracking(ctx, TrackingFilter{TransactionHash: "0x3"})
+	if len(invalidated) != 1 || invalidated[0].Status != models.StatusInvalidated {
+		t.Errorf("pending transfer = %v, want it invalidated", invalidated)
+	}
+	if count, _ := s.CountTracking(ctx, TrackingFilter{Chain: "bsc"}); count != 1 {
+		t.Errorf("other chain has %d transfers, want 1", count)
+	}
+	if _, found, _ := s.GetBlock(ctx, "eth", 2); found {
+		t.Error("block 2 is still recorded")
+	}
+	if checkpoint, _, _ := s.GetCheckpoint(ctx, "eth"); checkpoint != 1 {
+		t.Errorf("checkpoint = %d, want 1", checkpoint)
+	}
+
+	// an invalidated transfer is stored again when it reappears
+	saved, err := s.SaveTracking(ctx, transfer("eth", "0x3", 0, 2, 1), nil)
+	if err != nil || !saved {
+		t.Errorf("save of invalidated transfer = %v, %v, want true", saved, err)
+	}
+}
+
+func TestMemoryPromotePending(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	for _, hash := range []string{"0x1", "0x2", "0x3"} {
+		pending := transfer("eth", hash, 0, 5, 1)
+		pending.Status = models.StatusPending
+		if hash == "0x3" {
+			pending.BlockNumber = 9
+		}
+		_, _ = s.SaveTracking(ctx, pending, nil)
+	}
+	_ = s.SaveBlock(ctx, &models.TrackedBlock{Chain: "eth", BlockNumber: 5, BlockHash: "block0x1"})
+
+	confirmed, err := s.PromotePending(ctx, "eth", 8, alertOnce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmed) != 1 || confirmed[0].TransactionHash != "0x1" {
+		t.Fatalf("confirmed %v, want the transfer of 0x1", confirmed)
+	}
+	if outbox, _ := s.CountOutbox(ctx, ""); outbox != 1 {
+		t.Errorf("queued %d alerts, want 1", outbox)
+	}
+	for hash, status := range map[string]string{
+		"0x1": models.StatusConfirmed,
+		"0x2": models.StatusInvalidated,
+		"0x3": models.StatusPending,
+	} {
+		tracking, _ := s.QueryTracking(ctx, TrackingFilter{TransactionHash: hash})
+		if len(tracking) != 1 || tracking[0].Status != status {
+			t.Errorf("transfer of %s = %v, want status %s", hash, tracking, status)
+		}
+	}
+}
+
+func TestMemoryWallets(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+
+	created, err := s.SaveWallet(ctx, &models.Wallet{Chain: "eth", Address: "0xa", Label: "old"})
+	if err != nil || !created {
+		t.Fatalf("SaveWallet = %v, %v, want true", created, err)
+	}
+	wallet := &models.Wallet{Chain: "eth", Address: "0xa", Label: "new"}
+	created, _ = s.SaveWallet(ctx, wallet)
+	if created || wallet.ID != 1 || wallet.Label != "new" {
+		t.Errorf("second SaveWallet = %v, %+v, want the relabelled wallet 1", created, wallet)
+	}
+	_, _ = s.SaveWallet(ctx, &models.Wallet{Chain: "bsc", Address: "0xa"})
+
+	wallets, _ := s.GetWallets(ctx, "eth")
+	if len(wallets) != 1 || wallets[0].Label != "new" {
+		t.Errorf("GetWallets = %+v, want the relabelled wallet", wallets)
+	}
+	if all, _ := s.GetWallets(ctx, ""); len(all) != 2 {
+		t.Errorf("GetWallets of every chain returned %d wallets, want 2", len(all))
+	}
+	deleted, _ := s.DeleteWallet(ctx, "eth", "0xa")
+	if !deleted {
+		t.Error("DeleteWallet = false, want true")
+	}
+	deleted, _ = s.DeleteWallet(ctx, "eth", "0xa")
+	if deleted {
+		t.Error("second DeleteWallet = true, want false")
+	}
+}
+
+func TestMemoryOutbox(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	now := time.Now()
+	_, _ = s.SaveTracking(ctx, transfer("eth", "0x1", 0, 1, 1), func(*models.TrackingInformation) []models.OutboxMessage {
+		return []models.OutboxMessage{
+			{Notifier: "due", Status: models.OutboxPending, NextAttemptAt: now},
+			{Notifier: "later", Status: models.OutboxPending, NextAttemptAt: now.Add(time.Hour)},
+		}
+	})
+
+	due, err := s.GetDueOutbox(ctx, now, 10)
+	if err != nil || len(due) != 1 || due[0].Notifier != "due" {
+		t.Fatalf("GetDueOutbox = %+v, %v, want the due message", due, err)
+	}
+	message := due[0]
+	message.Status = models.OutboxDead
+	message.Attempts = 8
+	_ = s.UpdateOutbox(ctx, &message)
+	if dead, _ := s.CountOutbox(ctx, models.OutboxDead); dead != 1 {
+		t.Errorf("%d dead messages, want 1", dead)
+	}
+
+	found, _ := s.RetryOutbox(ctx, message.ID)
+	if !found {
+		t.Fatal("RetryOutbox = false, want true")
+	}
+	due, _ = s.GetDueOutbox(ctx, time.Now(), 10)
+	if len(due) != 1 || due[0].Attempts != 0 {
+		t.Errorf("GetDueOutbox after retry = %+v, want the message with no attempt", due)
+	}
+	if found, _ := s.RetryOutbox(ctx, 99); found {
+		t.Error("RetryOutbox of an unknown message = true, want false")
+	}
+}
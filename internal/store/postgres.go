@@ -0,0 +1,475 @@
+package store
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"strings"
+	"time"
+)
+
+// Postgres is the TrackingStore backed by the bun database.
+type Postgres struct {
+	db *bun.DB
+}
+
+func NewPostgres(db *bun.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (s *Postgres) SaveTracking(ctx context.Context, trackingInfo *models.TrackingInformation, alert AlertFunc) (bool, error) {
+	var inserted bool
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewInsert().
+			Model(trackingInfo).
+			On(`CONFLICT ("chain", "transactionHash", "logIndex") DO UPDATE`).
+			Set(`"blockNumber" = EXCLUDED."blockNumber"`).
+			Set(`"blockHash" = EXCLUDED."blockHash"`).
+			Set(`"blockTime" = EXCLUDED."blockTime"`).
+			Set(`"transactionIndex" = EXCLUDED."transactionIndex"`).
+			Set(`"gasUsed" = EXCLUDED."gasUsed"`).
+			Set(`"effectiveGasPrice" = EXCLUDED."effectiveGasPrice"`).
+			Set(`"fee" = EXCLUDED."fee"`).
+			Set(`"status" = EXCLUDED."status"`).
+			Set(`"fromLabel" = EXCLUDED."fromLabel"`).
+			Set(`"toLabel" = EXCLUDED."toLabel"`).
+			Where(`tracking_information."status" = ?`, models.StatusInvalidated).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		inserted = rowsAffected > 0
+		if !inserted {
+			return nil
+		}
+		return queueAlerts(ctx, tx, alert, []models.TrackingInformation{*trackingInfo})
+	})
+	if err != nil {
+		return false, fmt.Errorf("error inserting data into database: %w", err)
+	}
+	return inserted, nil
+}
+
+// queueAlerts writes the alerts of alert for each transfer to the outbox.
+func queueAlerts(ctx context.Context, tx bun.Tx, alert AlertFunc, tracking []models.TrackingInformation) error {
+	if alert == nil {
+		return nil
+	}
+	var messages []models.OutboxMessage
+	for i := range tracking {
+		messages = append(messages, alert(&tracking[i])...)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	_, err := tx.NewInsert().Model(&messages).Exec(ctx)
+	return err
+}
+
+func (s *Postgres) QueryTracking(ctx context.Context, filter TrackingFilter) ([]models.TrackingInformation, error) {
+	var tracking []models.TrackingInformation
+	query := applyTrackingFilter(s.db.NewSelect().Model(&tracking), filter)
+
+	key, placeholder := sortKey(filter.Sort)
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		if filter.After.Sort == SortID {
+			query = query.Where(`"id" `+comparison+` ?`, filter.After.ID)
+		} else {
+			query = query.Where(`(`+key+`, "id") `+comparison+` (`+placeholder+`, ?)`, filter.After.Value, filter.After.ID)
+		}
+	}
+	if key != `"id"` {
+		query = query.OrderExpr(key + " " + direction)
+	}
+	query = query.OrderExpr(`"id" ` + direction)
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	err := query.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tracking, nil
+}
+
+// sortKey returns the column expression of a sort order, and the placeholder
+// a cursor value is compared with. Legacy rows without block details sort as
+// the zero values the tracker stores for them.
+func sortKey(sort string) (string, string) {
+	switch sort {
+	case SortBlockNumber:
+		return `COALESCE("blockNumber", 0)`, `?::bigint`
+	case SortBlockTime:
+		return `COALESCE("blockTime", '0001-01-01T00:00:00Z')`, `?::timestamptz`
+	case SortAmount:
+		return `"amount"`, `?::numeric`
+	}
+	return `"id"`, `?`
+}
+
+func (s *Postgres) CountTracking(ctx context.Context, filter TrackingFilter) (int, error) {
+	return applyTrackingFilter(s.db.NewSelect().Model((*models.TrackingInformation)(nil)), filter).
+		Count(ctx)
+}
+
+func applyTrackingFilter(query *bun.SelectQuery, filter TrackingFilter) *bun.SelectQuery {
+	if filter.Chain != "" {
+		query = query.Where(`"chain" = ?`, filter.Chain)
+	}
+	if filter.Type != "" {
+		query = query.Where(`LOWER("type") = ?`, strings.ToLower(filter.Type))
+	}
+	if filter.Token != "" {
+		query = query.Where(`"token" = ?`, strings.ToLower(filter.Token))
+	}
+	if filter.Symbol != "" {
+		query = query.Where(`LOWER("symbol") = ?`, strings.ToLower(filter.Symbol))
+	}
+	if filter.From != "" {
+		query = query.Where(`"from" = ?`, strings.ToLower(filter.From))
+	}
+	if filter.To != "" {
+		query = query.Where(`"to" = ?`, strings.ToLower(filter.To))
+	}
+	if filter.Address != "" {
+		address := strings.ToLower(filter.Address)
+		query = query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where(`"from" = ?`, address).WhereOr(`"to" = ?`, address)
+		})
+	}
+	if filter.TransactionHash != "" {
+		query = query.Where(`"transactionHash" = ?`, strings.ToLower(filter.TransactionHash))
+	}
+	if filter.MinAmount != nil {
+		query = query.Where(`"amount" >= ?::numeric`, filter.MinAmount.String())
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where(`"amount" <= ?::numeric`, filter.MaxAmount.String())
+	}
+	if filter.FromBlock != nil {
+		query = query.Where(`"blockNumber" >= ?`, *filter.FromBlock)
+	}
+	if filter.ToBlock != nil {
+		query = query.Where(`"blockNumber" <= ?`, *filter.ToBlock)
+	}
+	if filter.FromTime != nil {
+		query = query.Where(`"blockTime" >= ?`, *filter.FromTime)
+	}
+	if filter.ToTime != nil {
+		query = query.Where(`"blockTime" < ?`, *filter.ToTime)
+	}
+	return query
+}
+
+func (s *Postgres) DeleteTracking(ctx context.Context, transactionHash string) (int64, error) {
+	res, err := s.db.NewDelete().
+		Model((*models.TrackingInformation)(nil)).
+		Where(`LOWER("transactionHash") = ?`, strings.ToLower(transactionHash)).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Postgres) GetCheckpoint(ctx context.Context, chain string) (uint64, bool, error) {
+	checkpoint := new(models.Checkpoint)
+	err := s.db.NewSelect().
+		Model(checkpoint).
+		Where(`"chain" = ?`, chain).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	return checkpoint.BlockNumber, true, nil
+}
+
+func (s *Postgres) SaveCheckpoint(ctx context.Context, chain string, blockNumber uint64) error {
+	checkpoint := &models.Checkpoint{
+		Chain:       chain,
+		BlockNumber: blockNumber,
+		UpdatedAt:   time.Now(),
+	}
+	_, err := s.db.NewInsert().
+		Model(checkpoint).
+		On(`CONFLICT ("chain") DO UPDATE`).
+		Set(`"blockNumber" = EXCLUDED."blockNumber"`).
+		Set(`"updatedAt" = EXCLUDED."updatedAt"`).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *Postgres) GetWallets(ctx context.Context, chain string) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	query := s.db.NewSelect().Model(&wallets).Order("id")
+	if chain != "" {
+		query = query.Where(`"chain" = ?`, chain)
+	}
+	err := query.Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+func (s *Postgres) SaveWallet(ctx context.Context, wallet *models.Wallet) (bool, error) {
+	created, err := s.saveLabelled(ctx, wallet, wallet.Chain, wallet.Address)
+	if err != nil {
+		return false, fmt.Errorf("failed to save wallet: %w", err)
+	}
+	return created, nil
+}
+
+func (s *Postgres) DeleteWallet(ctx context.Context, chain string, address string) (bool, error) {
+	deleted, err := s.deleteLabelled(ctx, (*models.Wallet)(nil), chain, address)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete wallet: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *Postgres) GetTrackedTokens(ctx context.Context, chain string) ([]models.TrackedToken, error) {
+	var trackedTokens []models.TrackedToken
+	query := s.db.NewSelect().Model(&trackedTokens).Order("id")
+	if chain != "" {
+		query = query.Where(`"chain" = ?`, chain)
+	}
+	err := query.Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked tokens: %w", err)
+	}
+	return trackedTokens, nil
+}
+
+func (s *Postgres) SaveTrackedToken(ctx context.Context, trackedToken *models.TrackedToken) (bool, error) {
+	created, err := s.saveLabelled(ctx, trackedToken, trackedToken.Chain, trackedToken.Address)
+	if err != nil {
+		return false, fmt.Errorf("failed to save tracked token: %w", err)
+	}
+	return created, nil
+}
+
+func (s *Postgres) DeleteTrackedToken(ctx context.Context, chain string, address string) (bool, error) {
+	deleted, err := s.deleteLabelled(ctx, (*models.TrackedToken)(nil), chain, address)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tracked token: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *Postgres) GetToken(ctx context.Context, chain string, address string) (*models.Token, bool, error) {
+	token := new(models.Token)
+	err := s.db.NewSelect().
+		Model(token).
+		Where(`"chain" = ?`, chain).
+		Where(`"address" = ?`, address).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, true, nil
+}
+
+func (s *Postgres) SaveToken(ctx context.Context, token *models.Token) error {
+	_, err := s.db.NewInsert().
+		Model(token).
+		On(`CONFLICT ("chain", "address") DO UPDATE`).
+		Set(`"name" = EXCLUDED."name"`).
+		Set(`"symbol" = EXCLUDED."symbol"`).
+		Set(`"decimals" = EXCLUDED."decimals"`).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// saveLabelled inserts a labelled address model, a wallet or a token, or
+// updates the label of the existing row with the same chain and address.
+// model is filled with the stored row.
+func (s *Postgres) saveLabelled(ctx context.Context, model interface{}, chain string, address string) (bool, error) {
+	res, err := s.db.NewInsert().
+		Model(model).
+		On(`CONFLICT ("chain", "address") DO NOTHING`).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected > 0 {
+		return true, nil
+	}
+	_, err = s.db.NewUpdate().
+		Model(model).
+		Column("label").
+		Where(`"chain" = ?`, chain).
+		Where(`"address" = ?`, address).
+		Returning("*").
+		Exec(ctx)
+	return false, err
+}
+
+func (s *Postgres) deleteLabelled(ctx context.Context, model interface{}, chain string, address string) (bool, error) {
+	res, err := s.db.NewDelete().
+		Model(model).
+		Where(`"chain" = ?`, chain).
+		Where(`"address" = ?`, address).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *Postgres) GetDueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := s.db.NewSelect().
+		Model(&messages).
+		Where(`"status" = ?`, models.OutboxPending).
+		Where(`"nextAttemptAt" <= ?`, now).
+		Order("id").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *Postgres) UpdateOutbox(ctx context.Context, message *models.OutboxMessage) error {
+	_, err := s.db.NewUpdate().
+		Model(message).
+		Column("status", "attempts", "nextAttemptAt", "lastError", "deliveredAt").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+	return nil
+}
+
+func (s *Postgres) QueryOutbox(ctx context.Context, status string, limit int, offset int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	query := s.db.NewSelect().Model(&messages).Order("id DESC")
+	if status != "" {
+		query = query.Where(`"status" = ?`, status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	err := query.Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *Postgres) CountOutbox(ctx context.Context, status string) (int, error) {
+	query := s.db.NewSelect().Model((*models.OutboxMessage)(nil))
+	if status != "" {
+		query = query.Where(`"status" = ?`, status)
+	}
+	return query.Count(ctx)
+}
+
+func (s *Postgres) RetryOutbox(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.NewUpdate().
+		Model((*models.OutboxMessage)(nil)).
+		Set(`"status" = ?`, models.OutboxPending).
+		Set(`"attempts" = 0`).
+		Set(`"nextAttemptAt" = ?`, time.Now()).
+		Where(`"id" = ?`, id).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to retry outbox message: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *Postgres) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	err := s.db.NewSelect().Model(&rules).Order("id").Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *Postgres) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	_, err := s.db.NewInsert().Model(rule).Returning("*").Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return nil
+}
+
+func (s *Postgres) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error) {
+	res, err := s.db.NewUpdate().
+		Model(rule).
+		ExcludeColumn("id", "createdAt").
+		WherePK().
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *Postgres) DeleteAlertRule(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.NewDelete().
+		Model((*models.AlertRule)(nil)).
+		Where(`"id" = ?`, id).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
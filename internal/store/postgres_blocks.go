@@ -0,0 +1,145 @@
+package store
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+func (s *Postgres) GetBlock(ctx context.Context, chain string, blockNumber uint64) (*models.TrackedBlock, bool, error) {
+	block := new(models.TrackedBlock)
+	err := s.db.NewSelect().
+		Model(block).
+		Where(`"chain" = ?`, chain).
+		Where(`"blockNumber" = ?`, blockNumber).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get block: %w", err)
+	}
+	return block, true, nil
+}
+
+func (s *Postgres) SaveBlock(ctx context.Context, block *models.TrackedBlock) error {
+	_, err := s.db.NewInsert().
+		Model(block).
+		On(`CONFLICT ("chain", "blockNumber") DO UPDATE`).
+		Set(`"blockHash" = EXCLUDED."blockHash"`).
+		Set(`"parentHash" = EXCLUDED."parentHash"`).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save block: %w", err)
+	}
+	return nil
+}
+
+func (s *Postgres) PruneBlocks(ctx context.Context, chain string, blockNumber uint64) error {
+	_, err := s.db.NewDelete().
+		Model((*models.TrackedBlock)(nil)).
+		Where(`"chain" = ?`, chain).
+		Where(`"blockNumber" < ?`, blockNumber).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to prune blocks: %w", err)
+	}
+	return nil
+}
+
+func (s *Postgres) RollbackBlocks(ctx context.Context, chain string, blockNumber uint64, alert AlertFunc) ([]models.TrackingInformation, error) {
+	var removed []models.TrackingInformation
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model((*models.TrackingInformation)(nil)).
+			Set(`"status" = ?`, models.StatusInvalidated).
+			Where(`"chain" = ?`, chain).
+			Where(`"blockNumber" > ?`, blockNumber).
+			Where(`"status" = ?`, models.StatusPending).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model(&removed).
+			Where(`"chain" = ?`, chain).
+			Where(`"blockNumber" > ?`, blockNumber).
+			Where(`"status" = ?`, models.StatusConfirmed).
+			Returning("*").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		err = queueAlerts(ctx, tx, alert, removed)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.TrackedBlock)(nil)).
+			Where(`"chain" = ?`, chain).
+			Where(`"blockNumber" > ?`, blockNumber).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*models.Checkpoint)(nil)).
+			Set(`"blockNumber" = ?`, blockNumber).
+			Set(`"updatedAt" = ?`, time.Now()).
+			Where(`"chain" = ?`, chain).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rollback blocks: %w", err)
+	}
+	return removed, nil
+}
+
+func (s *Postgres) PromotePending(ctx context.Context, chain string, blockNumber uint64, alert AlertFunc) ([]models.TrackingInformation, error) {
+	var confirmed []models.TrackingInformation
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		recorded := tx.NewSelect().
+			Model((*models.TrackedBlock)(nil)).
+			ColumnExpr("1").
+			Where(`b."chain" = tracking_information."chain"`).
+			Where(`b."blockNumber" = tracking_information."blockNumber"`).
+			Where(`b."blockHash" = tracking_information."blockHash"`)
+
+		_, err := tx.NewUpdate().
+			Model((*models.TrackingInformation)(nil)).
+			Set(`"status" = ?`, models.StatusInvalidated).
+			Where(`"chain" = ?`, chain).
+			Where(`"blockNumber" <= ?`, blockNumber).
+			Where(`"status" = ?`, models.StatusPending).
+			Where("NOT EXISTS (?)", recorded).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model(&confirmed).
+			Set(`"status" = ?`, models.StatusConfirmed).
+			Where(`"chain" = ?`, chain).
+			Where(`"blockNumber" <= ?`, blockNumber).
+			Where(`"status" = ?`, models.StatusPending).
+			Returning("*").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		return queueAlerts(ctx, tx, alert, confirmed)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote pending transfers: %w", err)
+	}
+	return confirmed, nil
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"Intermediate_web3/internal/models"
+	"context"
+	"time"
+)
+
+// TrackingStore persists the tracked transfers and the progress of the
+// tracker.
+type TrackingStore interface {
+	// SaveTracking stores a transfer event unless it is already stored, and
+	// reports whether it was new. An invalidated event is stored again. When
+	// the event is new, the alerts of alert are queued in the same
+	// transaction; alert may be nil.
+	SaveTracking(ctx context.Context, trackingInfo *models.TrackingInformation, alert AlertFunc) (bool, error)
+	QueryTracking(ctx context.Context, filter TrackingFilter) ([]models.TrackingInformation, error)
+	CountTracking(ctx context.Context, filter TrackingFilter) (int, error)
+	// DeleteTracking removes the transfers of a transaction, matching the hash
+	// case-insensitively, and returns how many were removed.
+	DeleteTracking(ctx context.Context, transactionHash string) (int64, error)
+
+	// GetCheckpoint returns the last fully processed block of chain. The
+	// boolean is false when the chain has no checkpoint yet.
+	GetCheckpoint(ctx context.Context, chain string) (uint64, bool, error)
+	SaveCheckpoint(ctx context.Context, chain string, blockNumber uint64) error
+
+	// GetBlock returns the recorded block of chain at blockNumber. The boolean
+	// is false when the block was never recorded or has been pruned.
+	GetBlock(ctx context.Context, chain string, blockNumber uint64) (*models.TrackedBlock, bool, error)
+	// SaveBlock records a processed block, replacing any block previously
+	// recorded at the same height.
+	SaveBlock(ctx context.Context, block *models.TrackedBlock) error
+	// PruneBlocks removes the recorded blocks of chain below blockNumber.
+	PruneBlocks(ctx context.Context, chain string, blockNumber uint64) error
+	// RollbackBlocks removes everything recorded for chain above blockNumber:
+	// the confirmed transfers, the block hashes, and moves the checkpoint back
+	// to blockNumber. Pending transfers are kept but invalidated. It returns
+	// the confirmed transfers that were removed, and queues the alerts of
+	// alert for each of them.
+	RollbackBlocks(ctx context.Context, chain string, blockNumber uint64, alert AlertFunc) ([]models.TrackingInformation, error)
+	// PromotePending settles the pending transfers of chain up to
+	// blockNumber. Transfers whose block is still the recorded one are
+	// confirmed, returned and alerted with alert; the others were orphaned
+	// and are invalidated.
+	PromotePending(ctx context.Context, chain string, blockNumber uint64, alert AlertFunc) ([]models.TrackingInformation, error)
+
+	// GetWallets returns the stored watched wallets of chain, or of every
+	// chain if chain is empty.
+	GetWallets(ctx context.Context, chain string) ([]models.Wallet, error)
+	// SaveWallet stores a watched wallet, or updates the label of the stored
+	// wallet with the same chain and address, and reports whether it was new.
+	SaveWallet(ctx context.Context, wallet *models.Wallet) (bool, error)
+	// DeleteWallet removes a stored wallet and reports whether it existed.
+	DeleteWallet(ctx context.Context, chain string, address string) (bool, error)
+
+	// GetTrackedTokens returns the stored tracked tokens of chain, or of every
+	// chain if chain is empty.
+	GetTrackedTokens(ctx context.Context, chain string) ([]models.TrackedToken, error)
+	// SaveTrackedToken stores a tracked token, or updates the label of the
+	// stored token with the same chain and address, and reports whether it was
+	// new.
+	SaveTrackedToken(ctx context.Context, trackedToken *models.TrackedToken) (bool, error)
+	// DeleteTrackedToken removes a stored token and reports whether it existed.
+	DeleteTrackedToken(ctx context.Context, chain string, address string) (bool, error)
+
+	// GetToken returns the stored metadata of the token of chain at address.
+	// The boolean is false when the token was never resolved.
+	GetToken(ctx context.Context, chain string, address string) (*models.Token, bool, error)
+	// SaveToken stores the metadata of a token, replacing any stored before.
+	SaveToken(ctx context.Context, token *models.Token) error
+
+	// GetDueOutbox returns up to limit pending outbox messages whose next
+	// attempt is due at now, oldest first.
+	GetDueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxMessage, error)
+	// UpdateOutbox saves the delivery state of an outbox message.
+	UpdateOutbox(ctx context.Context, message *models.OutboxMessage) error
+	// QueryOutbox returns the outbox messages with status, or all of them if
+	// status is empty, newest first.
+	QueryOutbox(ctx context.Context, status string, limit int, offset int) ([]models.OutboxMessage, error)
+	CountOutbox(ctx context.Context, status string) (int, error)
+	// RetryOutbox queues a dead or delivered outbox message again, and
+	// reports whether it exists.
+	RetryOutbox(ctx context.Context, id int64) (bool, error)
+
+	GetAlertRules(ctx context.Context) ([]models.AlertRule, error)
+	CreateAlertRule(ctx context.Context, rule *models.AlertRule) error
+	// UpdateAlertRule replaces the alert rule with the ID of rule, and
+	// reports whether it exists.
+	UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error)
+	DeleteAlertRule(ctx context.Context, id int64) (bool, error)
+}
+
+// AlertFunc returns the alerts to queue in the outbox for a transfer.
+type AlertFunc func(trackingInfo *models.TrackingInformation) []models.OutboxMessage
+
+var (
+	_ TrackingStore = (*Postgres)(nil)
+	_ TrackingStore = (*Memory)(nil)
+)
+
+// Sort orders of tracked transfers. Transfers with the same sort value are
+// ordered by id, which follows the order they were stored in.
+const (
+	SortID          = "id"
+	SortBlockNumber = "blockNumber"
+	SortBlockTime   = "blockTime"
+	SortAmount      = "amount"
+)
+
+// ValidSort reports whether sort is one of the supported sort orders.
+func ValidSort(sort string) bool {
+	switch sort {
+	case SortID, SortBlockNumber, SortBlockTime, SortAmount:
+		return true
+	}
+	return false
+}
+
+// TrackingFilter selects tracked transfers. Set fields are combined with AND.
+// Type and Symbol match case-insensitively, addresses and hashes are
+// lowercased. Address matches either party of a transfer. Amounts are raw
+// integer amounts, block bounds are inclusive and FromTime is inclusive while
+// ToTime is exclusive.
+type TrackingFilter struct {
+	Chain           string
+	Type            string
+	Token           string
+	Symbol          string
+	From            string
+	To              string
+	Address         string
+	TransactionHash string
+	MinAmount       *models.BigInt
+	MaxAmount       *models.BigInt
+	FromBlock       *uint64
+	ToBlock         *uint64
+	FromTime        *time.Time
+	ToTime          *time.Time
+
+	// Sort is one of the Sort orders, SortID if empty.
+	Sort string
+	Desc bool
+	// After resumes the listing after the transfer the cursor points to. It
+	// must have been made for the same sort order.
+	After *Cursor
+	// Limit is the maximum number of transfers returned, unlimited if 0.
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,89 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Component is a long-running part of the application. Run must block until
+// ctx is cancelled or the component fails, and return nil on a clean stop.
+type Component struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Run starts every component with a shared context and waits for all of them
+// to return. The first component to stop, with or without an error, cancels
+// the context of the others. The first error is returned.
+func Run(ctx context.Context, components ...Component) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, component := range components {
+		wg.Add(1)
+		go func(component Component) {
+			defer wg.Done()
+			defer cancel()
+
+			err := runComponent(ctx, component)
+			if err != nil {
+				fmt.Printf("Component %s stopped with error: %v\n", component.Name, err)
+				once.Do(func() {
+					firstErr = fmt.Errorf("%s: %w", component.Name, err)
+				})
+				return
+			}
+			fmt.Printf("Component %s stopped\n", component.Name)
+		}(component)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func runComponent(ctx context.Context, component Component) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return component.Run(ctx)
+}
+
+// HTTPServer returns a component function that serves srv until ctx is
+// cancelled, then shuts it down, waiting up to shutdownTimeout for in-flight
+// requests to finish.
+func HTTPServer(srv *http.Server, shutdownTimeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() {
+			fmt.Printf("Listening and serving HTTP on %s\n", srv.Addr)
+			errCh <- srv.ListenAndServe()
+		}()
+
+		select {
+		case err := <-errCh:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			return fmt.Errorf("failed to shutdown http server: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,341 @@
+package telegrambot
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/service"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/watchlist"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// pollTimeout is how long, in seconds, a getUpdates call waits for
+	// commands. It stays below the timeout of the notifier HTTP client.
+	pollTimeout = 5
+	// retryDelay is how long the bot waits after failing to get commands.
+	retryDelay = 10 * time.Second
+	// defaultLast and maxLast are the number of transfers listed by /last.
+	defaultLast = 5
+	maxLast     = 20
+)
+
+const help = `Commands:
+/watch [chain] <address> [label] - watch a wallet
+/unwatch [chain] <address> - stop watching a wallet
+/tokens [chain] - list the tracked tokens
+/addtoken [chain] <address> [label] - track a token
+/status - head, last block and lag of each chain
+/last [N] - the last N transfers
+/mute <duration> - mute the alerts of this bot, e.g. 1h, or off
+The chain can be left out when a single chain is tracked.`
+
+// Bot answers the commands sent to the bot of a telegram notifier, by long
+// polling. Only the chat of the notifier and its command chats are allowed
+// to send commands. The notifier is looked up on each poll, so that the bot
+// follows the reloads of the notifiers.
+type Bot struct {
+	name      string
+	store     store.TrackingStore
+	watchlist *watchlist.Registry
+	tracker   *service.Tracker
+	notifiers *notify.Registry
+}
+
+// New returns the bot of the telegram notifier named name.
+func New(name string, trackingStore store.TrackingStore, registry *watchlist.Registry, tracker *service.Tracker, notifiers *notify.Registry) *Bot {
+	return &Bot{
+		name:      name,
+		store:     trackingStore,
+		watchlist: registry,
+		tracker:   tracker,
+		notifiers: notifiers,
+	}
+}
+
+// Run answers commands until ctx is cancelled.
+func (b *Bot) Run(ctx context.Context) error {
+	offset := 0
+	var current *notify.Telegram
+	for ctx.Err() == nil {
+		telegram, chats, err := b.lookup()
+		if err == nil {
+			// the offsets of updates belong to a bot
+			if current != nil && !current.SameBot(telegram) {
+				offset = 0
+			}
+			current = telegram
+			var updates []tgbotapi.Update
+			updates, err = getUpdates(telegram, offset)
+			if err == nil {
+				for _, update := range updates {
+					offset = update.UpdateID + 1
+					b.handle(ctx, telegram, chats, update)
+				}
+				continue
+			}
+		}
+		fmt.Printf("[bot %s] Failed to get commands, retrying in %v: %v\n", b.name, retryDelay, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(retryDelay):
+		}
+	}
+	return nil
+}
+
+// lookup returns the notifier of the bot as currently configured, and the
+// chats allowed to send commands.
+func (b *Bot) lookup() (*notify.Telegram, map[int64]bool, error) {
+	notifier, ok := b.notifiers.Get(b.name)
+	config, configured := b.notifiers.Config(b.name)
+	if !ok || !configured {
+		return nil, nil, fmt.Errorf("notifier %s not found", b.name)
+	}
+	telegram, ok := notifier.(*notify.Telegram)
+	if !ok {
+		return nil, nil, fmt.Errorf("notifier %s is not a telegram notifier", b.name)
+	}
+	if !config.Commands {
+		return nil, nil, fmt.Errorf("notifier %s does not take commands", b.name)
+	}
+	chats := map[int64]bool{telegram.ChatID(): true}
+	for _, chat := range config.CommandChats {
+		id, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse command chat ID: %v", err)
+		}
+		chats[id] = true
+	}
+	return telegram, chats, nil
+}
+
+func getUpdates(telegram *notify.Telegram, offset int) ([]tgbotapi.Update, error) {
+	bot, err := telegram.Bot()
+	if err != nil {
+		return nil, err
+	}
+	return bot.GetUpdates(tgbotapi.UpdateConfig{
+		Offset:         offset,
+		Timeout:        pollTimeout,
+		AllowedUpdates: []string{"message"},
+	})
+}
+
+// handle answers the command of update with telegram, if it is one from
+// chats.
+func (b *Bot) handle(ctx context.Context, telegram *notify.Telegram, chats map[int64]bool, update tgbotapi.Update) {
+	message := update.Message
+	if message == nil || !message.IsCommand() {
+		return
+	}
+	if !chats[message.Chat.ID] {
+		fmt.Printf("[bot %s] Ignored /%s from unauthorized chat %d\n", b.name, message.Command(), message.Chat.ID)
+		return
+	}
+
+	reply := b.command(ctx, message.Command(), strings.Fields(message.CommandArguments()))
+	bot, err := telegram.Bot()
+	if err != nil {
+		fmt.Printf("[bot %s] Failed to reply: %v\n", b.name, err)
+		return
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, reply)
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableWebPagePreview = true
+	_, err = bot.Send(msg)
+	if err != nil {
+		fmt.Printf("[bot %s] Failed to reply: %v\n", b.name, err)
+	}
+}
+
+// command runs a command and returns the reply.
+func (b *Bot) command(ctx context.Context, command string, args []string) string {
+	switch command {
+	case "start", "help":
+		return help
+	case "watch":
+		return b.watch(ctx, args)
+	case "unwatch":
+		return b.unwatch(ctx, args)
+	case "tokens":
+		return b.tokens(ctx, args)
+	case "addtoken":
+		return b.addToken(ctx, args)
+	case "status":
+		return b.status(ctx)
+	case "last":
+		return b.last(ctx, args)
+	case "mute":
+		return b.mute(args)
+	}
+	return fmt.Sprintf("Unknown command /%s, see /help", command)
+}
+
+// chainAddress reads the optional chain and the address starting args, and
+// returns the remaining arguments.
+func (b *Bot) chainAddress(args []string) (string, string, []string, error) {
+	chain := ""
+	if len(args) > 0 && b.watchlist.HasChain(args[0]) {
+		chain, args = args[0], args[1:]
+	} else {
+		chains := b.tracker.Chains()
+		if len(chains) != 1 {
+			return "", "", nil, fmt.Errorf("several chains are tracked, give the chain first: %s", strings.Join(chains, ", "))
+		}
+		chain = chains[0]
+	}
+	if len(args) == 0 {
+		return "", "", nil, fmt.Errorf("an address is required")
+	}
+	if !common.IsHexAddress(args[0]) {
+		return "", "", nil, fmt.Errorf("invalid address %s", args[0])
+	}
+	return chain, strings.ToLower(args[0]), args[1:], nil
+}
+
+func (b *Bot) watch(ctx context.Context, args []string) string {
+	chain, address, rest, err := b.chainAddress(args)
+	if err != nil {
+		return err.Error()
+	}
+	label := strings.Join(rest, " ")
+	_, err = b.store.SaveWallet(ctx, &models.Wallet{Chain: chain, Address: address, Label: label})
+	if err != nil {
+		return fmt.Sprintf("Failed to save wallet: %v", err)
+	}
+	b.watchlist.SetWallet(chain, address, label)
+	return fmt.Sprintf("Watching %s on %s", address, chain)
+}
+
+func (b *Bot) unwatch(ctx context.Context, args []string) string {
+	chain, address, _, err := b.chainAddress(args)
+	if err != nil {
+		return err.Error()
+	}
+	deleted, err := b.store.DeleteWallet(ctx, chain, address)
+	if err != nil {
+		return fmt.Sprintf("Failed to delete wallet: %v", err)
+	}
+	if !deleted {
+		return fmt.Sprintf("%s is not watched on %s, or only in the config file", address, chain)
+	}
+	b.watchlist.RemoveWallet(chain, address)
+	return fmt.Sprintf("Stopped watching %s on %s", address, chain)
+}
+
+func (b *Bot) tokens(ctx context.Context, args []string) string {
+	chains := b.tracker.Chains()
+	if len(args) > 0 {
+		if !slices.Contains(chains, args[0]) {
+			return fmt.Sprintf("unknown chain %s, tracked chains: %s", args[0], strings.Join(chains, ", "))
+		}
+		chains = args[:1]
+	}
+	var lines []string
+	for _, chain := range chains {
+		lines = append(lines, chain+":")
+		for _, address := range b.watchlist.Tokens(chain) {
+			line := "  " + address
+			token, found, err := b.store.GetToken(ctx, chain, address)
+			if err == nil && found && token.Symbol != "" {
+				line += " " + token.Symbol
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b *Bot) addToken(ctx context.Context, args []string) string {
+	chain, address, rest, err := b.chainAddress(args)
+	if err != nil {
+		return err.Error()
+	}
+	label := strings.Join(rest, " ")
+	_, err = b.tracker.ResolveToken(ctx, chain, address)
+	if err != nil {
+		return fmt.Sprintf("Failed to add token: %v", err)
+	}
+	_, err = b.store.SaveTrackedToken(ctx, &models.TrackedToken{Chain: chain, Address: address, Label: label})
+	if err != nil {
+		return fmt.Sprintf("Failed to save token: %v", err)
+	}
+	b.watchlist.SetToken(chain, address, label)
+	return fmt.Sprintf("Tracking token %s on %s", address, chain)
+}
+
+func (b *Bot) status(ctx context.Context) string {
+	var lines []string
+	for _, chain := range b.tracker.Chains() {
+		checkpoint, found, err := b.store.GetCheckpoint(ctx, chain)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: failed to get last block: %v", chain, err))
+			continue
+		}
+		last := "none"
+		if found {
+			last = strconv.FormatUint(checkpoint, 10)
+		}
+		head, ok := b.tracker.Head(chain)
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%s: head unknown, last block %s", chain, last))
+			continue
+		}
+		lag := "unknown"
+		if found && head >= checkpoint {
+			lag = strconv.FormatUint(head-checkpoint, 10)
+		}
+		lines = append(lines, fmt.Sprintf("%s: head %d, last block %s, lag %s", chain, head, last, lag))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b *Bot) last(ctx context.Context, args []string) string {
+	n := defaultLast
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < 1 {
+			return fmt.Sprintf("invalid number %s", args[0])
+		}
+		n = min(parsed, maxLast)
+	}
+	tracking, err := b.store.QueryTracking(ctx, store.TrackingFilter{Sort: store.SortID, Desc: true, Limit: n})
+	if err != nil {
+		return fmt.Sprintf("Failed to get transfers: %v", err)
+	}
+	if len(tracking) == 0 {
+		return "No transfer tracked yet"
+	}
+	lines := make([]string, 0, len(tracking))
+	for _, trackingInfo := range tracking {
+		lines = append(lines, fmt.Sprintf("%s block %d: %s %s from %s to %s, %s", trackingInfo.Chain, trackingInfo.BlockNumber,
+			trackingInfo.Amount.Format(trackingInfo.Decimals), trackingInfo.Symbol,
+			trackingInfo.From, trackingInfo.To, trackingInfo.TransactionHash))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b *Bot) mute(args []string) string {
+	if len(args) == 0 {
+		return "a duration is required, e.g. /mute 1h, or /mute off"
+	}
+	if args[0] == "off" {
+		b.notifiers.Mute(b.name, time.Time{})
+		return "Alerts unmuted"
+	}
+	duration, err := time.ParseDuration(args[0])
+	if err != nil || duration <= 0 {
+		return fmt.Sprintf("invalid duration %s", args[0])
+	}
+	until := time.Now().Add(duration)
+	b.notifiers.Mute(b.name, until)
+	return fmt.Sprintf("Alerts muted until %s", until.UTC().Format("2006-01-02 15:04 MST"))
+}
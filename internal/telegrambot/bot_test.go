@@ -0,0 +1,352 @@
+package telegrambot
+
+import (
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/service"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/watchlist"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	chatID   = 42
+	wallet   = "0x00000000000000000000000000000000000000aa"
+	stranger = 999
+)
+
+type reply struct {
+	token  string
+	chatID int64
+	text   string
+}
+
+// fakeTelegram is a Bot API serving queued commands to getUpdates and
+// recording the replies sent with sendMessage.
+type fakeTelegram struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	updates map[string][]map[string]interface{}
+	nextID  int
+	polled  map[string]bool
+	replies chan reply
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	fake := &fakeTelegram{
+		updates: make(map[string][]map[string]interface{}),
+		polled:  make(map[string]bool),
+		replies: make(chan reply, 100),
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// endpoint is the endpoint format of notifiers using the fake.
+func (f *fakeTelegram) endpoint() string {
+	return f.server.URL + "/bot%s/%s"
+}
+
+// send queues text as a command sent from chat to the bot of token.
+func (f *fakeTelegram) send(token string, chat int64, text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	command, _, _ := strings.Cut(text, " ")
+	f.updates[token] = append(f.updates[token], map[string]interface{}{
+		"update_id": f.nextID,
+		"message": map[string]interface{}{
+			"message_id": f.nextID,
+			"date":       0,
+			"chat":       map[string]interface{}{"id": chat, "type": "private"},
+			"text":       text,
+			"entities":   []map[string]interface{}{{"type": "bot_command", "offset": 0, "length": len(command)}},
+		},
+	})
+}
+
+func (f *fakeTelegram) wasPolled(token string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.polled[token]
+}
+
+func (f *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	token, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	r.ParseForm()
+	var result interface{}
+	switch method {
+	case "getMe":
+		result = map[string]interface{}{"id": 1, "is_bot": true, "first_name": "bot", "username": "bot"}
+	case "getUpdates":
+		offset, _ := strconv.Atoi(r.Form.Get("offset"))
+		f.mu.Lock()
+		f.polled[token] = true
+		var updates []map[string]interface{}
+		for _, update := range f.updates[token] {
+			if update["update_id"].(int) >= offset {
+				updates = append(updates, update)
+			}
+		}
+		f.mu.Unlock()
+		if len(updates) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		result = updates
+		if updates == nil {
+			result = []interface{}{}
+		}
+	case "sendMessage":
+		chat, _ := strconv.ParseInt(r.Form.Get("chat_id"), 10, 64)
+		f.replies <- reply{token: token, chatID: chat, text: r.Form.Get("text")}
+		result = map[string]interface{}{"message_id": 1, "date": 0, "chat": map[string]interface{}{"id": chat, "type": "private"}}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+// reply returns the next reply, failing if none is sent.
+func (f *fakeTelegram) reply(t *testing.T) reply {
+	t.Helper()
+	select {
+	case r := <-f.replies:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply")
+	}
+	return reply{}
+}
+
+func (f *fakeTelegram) noReply(t *testing.T) {
+	t.Helper()
+	select {
+	case r := <-f.replies:
+		t.Fatalf("unexpected reply %q", r.text)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+type fixture struct {
+	telegram  *fakeTelegram
+	store     *store.Memory
+	watchlist *watchlist.Registry
+	notifiers *notify.Registry
+}
+
+func notifierConfig(telegram *fakeTelegram, token string) models.NotifierConfig {
+	return models.NotifierConfig{Type: "telegram", Token: token, ChatID: strconv.Itoa(chatID), Endpoint: telegram.endpoint(), Commands: true}
+}
+
+// run starts the bots of configs against a fake Bot API, until the test ends.
+func run(t *testing.T, configs func(*fakeTelegram) map[string]models.NotifierConfig) *fixture {
+	fake := newFakeTelegram(t)
+	notifiers, err := notify.NewRegistry(configs(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackingStore := store.NewMemory()
+	registry := watchlist.New()
+	tracker := service.NewTracker(&models.Config{Chains: []models.ChainConfig{{Chain: "eth"}}}, trackingStore, registry, nil, notifiers, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewManager(trackingStore, registry, tracker, notifiers).Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return &fixture{telegram: fake, store: trackingStore, watchlist: registry, notifiers: notifiers}
+}
+
+func single(fake *fakeTelegram) map[string]models.NotifierConfig {
+	return map[string]models.NotifierConfig{"ops": notifierConfig(fake, "token")}
+}
+
+func TestUnauthorizedChatIsIgnored(t *testing.T) {
+	f := run(t, single)
+	f.telegram.send("token", stranger, "/watch "+wallet)
+	f.telegram.noReply(t)
+	if wallets, _ := f.store.GetWallets(context.Background(), ""); len(wallets) != 0 {
+		t.Errorf("wallets = %+v, want none", wallets)
+	}
+
+	// the chat of the notifier is still answered
+	f.telegram.send("token", chatID, "/help")
+	if r := f.telegram.reply(t); r.chatID != chatID || r.text != help {
+		t.Errorf("reply = %+v, want the help to chat %d", r, chatID)
+	}
+}
+
+func TestWatchAndUnwatch(t *testing.T) {
+	f := run(t, single)
+	ctx := context.Background()
+
+	f.telegram.send("token", chatID, "/watch "+strings.ToUpper(wallet[:2])+wallet[2:]+" cold wallet")
+	if r := f.telegram.reply(t); r.text != "Watching "+wallet+" on eth" {
+		t.Fatalf("reply = %q, want the wallet watched", r.text)
+	}
+	wallets, _ := f.store.GetWallets(ctx, "eth")
+	if len(wallets) != 1 || wallets[0].Address != wallet || wallets[0].Label != "cold wallet" {
+		t.Errorf("stored wallets = %+v, want the labelled wallet", wallets)
+	}
+	if label, ok := f.watchlist.WalletLabel("eth", wallet); !ok || label != "cold wallet" {
+		t.Errorf("watchlist label = %q, %v, want the wallet", label, ok)
+	}
+
+	f.telegram.send("token", chatID, "/unwatch eth "+wallet)
+	if r := f.telegram.reply(t); r.text != "Stopped watching "+wallet+" on eth" {
+		t.Fatalf("reply = %q, want the wallet unwatched", r.text)
+	}
+	if wallets, _ := f.store.GetWallets(ctx, "eth"); len(wallets) != 0 {
+		t.Errorf("stored wallets = %+v, want none", wallets)
+	}
+	if _, ok := f.watchlist.WalletLabel("eth", wallet); ok {
+		t.Error("wallet is still in the watchlist")
+	}
+
+	f.telegram.send("token", chatID, "/unwatch "+wallet)
+	if r := f.telegram.reply(t); !strings.Contains(r.text, "is not watched") {
+		t.Errorf("reply = %q, want the wallet not watched", r.text)
+	}
+}
+
+func TestLastIsLimited(t *testing.T) {
+	f := run(t, single)
+	for i := 0; i < maxLast+5; i++ {
+		_, err := f.store.SaveTracking(context.Background(), &models.TrackingInformation{
+			Chain:           "eth",
+			TransactionHash: fmt.Sprintf("0x%x", i),
+			Amount:          models.NewBigInt(big.NewInt(int64(i))),
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		command string
+		lines   int
+	}{
+		{command: "/last", lines: defaultLast},
+		{command: "/last 3", lines: 3},
+		{command: "/last 100", lines: maxLast},
+	} {
+		f.telegram.send("token", chatID, test.command)
+		r := f.telegram.reply(t)
+		lines := strings.Split(r.text, "\n")
+		if len(lines) != test.lines {
+			t.Errorf("%s listed %d transfers, want %d", test.command, len(lines), test.lines)
+		}
+		// the newest transfer comes first
+		if !strings.HasSuffix(lines[0], fmt.Sprintf("0x%x", maxLast+4)) {
+			t.Errorf("%s starts with %q, want the last transfer", test.command, lines[0])
+		}
+	}
+	for _, command := range []string{"/last 0", "/last many"} {
+		f.telegram.send("token", chatID, command)
+		if r := f.telegram.reply(t); !strings.HasPrefix(r.text, "invalid number") {
+			t.Errorf("%s answered %q, want an invalid number", command, r.text)
+		}
+	}
+}
+
+func TestMuteOff(t *testing.T) {
+	f := run(t, single)
+	f.telegram.send("token", chatID, "/mute 1h")
+	if r := f.telegram.reply(t); !strings.HasPrefix(r.text, "Alerts muted until") {
+		t.Fatalf("reply = %q, want the alerts muted", r.text)
+	}
+	if selected := f.notifiers.Select(nil); len(selected) != 0 {
+		t.Errorf("selected %v while muted, want none", selected)
+	}
+
+	f.telegram.send("token", chatID, "/mute off")
+	if r := f.telegram.reply(t); r.text != "Alerts unmuted" {
+		t.Fatalf("reply = %q, want the alerts unmuted", r.text)
+	}
+	if selected := f.notifiers.Select(nil); len(selected) != 1 || selected[0] != "ops" {
+		t.Errorf("selected %v after /mute off, want ops", selected)
+	}
+}
+
+func TestBotsFollowReloads(t *testing.T) {
+	f := run(t, single)
+	f.telegram.send("token", chatID, "/help")
+	if r := f.telegram.reply(t); r.token != "token" {
+		t.Fatalf("replied with %s, want token", r.token)
+	}
+
+	// a new token is used by the running bot, and a new notifier gets a bot
+	err := f.notifiers.Reload(map[string]models.NotifierConfig{
+		"ops":  notifierConfig(f.telegram, "rotated"),
+		"team": notifierConfig(f.telegram, "team"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"rotated", "team"} {
+		f.telegram.send(token, chatID, "/help")
+		if r := f.telegram.reply(t); r.token != token {
+			t.Errorf("replied with %s, want %s", r.token, token)
+		}
+	}
+
+	// a notifier no longer taking commands stops its bot
+	team := notifierConfig(f.telegram, "team")
+	team.Commands = false
+	err = f.notifiers.Reload(map[string]models.NotifierConfig{"ops": notifierConfig(f.telegram, "rotated"), "team": team})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	f.telegram.send("team", chatID, "/help")
+	f.telegram.noReply(t)
+	if !f.telegram.wasPolled("team") {
+		t.Error("bot of team never polled")
+	}
+}
+
+func TestAddTokenChecksContract(t *testing.T) {
+	f := run(t, single)
+	// the tracker of the chain is not running, so the token cannot be checked
+	f.telegram.send("token", chatID, "/addtoken "+wallet+" USDT")
+	if r := f.telegram.reply(t); !strings.HasPrefix(r.text, "Failed to add token") {
+		t.Errorf("reply = %q, want the token refused", r.text)
+	}
+	if tokens, _ := f.store.GetTrackedTokens(context.Background(), ""); len(tokens) != 0 {
+		t.Errorf("stored tokens = %+v, want none", tokens)
+	}
+	if f.watchlist.IsToken("eth", wallet) {
+		t.Error("token is in the watchlist")
+	}
+}
+
+func TestTokensChecksChain(t *testing.T) {
+	f := run(t, single)
+	f.watchlist.SetToken("eth", wallet, "")
+	f.telegram.send("token", chatID, "/tokens foo")
+	if r := f.telegram.reply(t); r.text != "unknown chain foo, tracked chains: eth" {
+		t.Errorf("reply = %q, want the chain refused", r.text)
+	}
+	f.telegram.send("token", chatID, "/tokens eth")
+	if r := f.telegram.reply(t); r.text != "eth:\n  "+wallet {
+		t.Errorf("reply = %q, want the tokens of eth", r.text)
+	}
+}
@@ -0,0 +1,78 @@
+package telegrambot
+
+import (
+	"Intermediate_web3/internal/notify"
+	"Intermediate_web3/internal/service"
+	"Intermediate_web3/internal/store"
+	"Intermediate_web3/internal/watchlist"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Manager runs a Bot for each telegram notifier taking commands, starting
+// and stopping them as the notifiers are reloaded.
+type Manager struct {
+	store     store.TrackingStore
+	watchlist *watchlist.Registry
+	tracker   *service.Tracker
+	notifiers *notify.Registry
+}
+
+func NewManager(trackingStore store.TrackingStore, registry *watchlist.Registry, tracker *service.Tracker, notifiers *notify.Registry) *Manager {
+	return &Manager{
+		store:     trackingStore,
+		watchlist: registry,
+		tracker:   tracker,
+		notifiers: notifiers,
+	}
+}
+
+// Run runs the bots until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	running := make(map[string]context.CancelFunc)
+	defer func() {
+		for _, cancel := range running {
+			cancel()
+		}
+		wg.Wait()
+	}()
+
+	for {
+		changed := m.notifiers.Changed()
+		wanted := make(map[string]bool)
+		for _, name := range m.notifiers.Names() {
+			config, ok := m.notifiers.Config(name)
+			if ok && config.Commands {
+				wanted[name] = true
+			}
+		}
+		for name, cancel := range running {
+			if !wanted[name] {
+				fmt.Printf("[bot %s] Stopped, its notifier no longer takes commands\n", name)
+				cancel()
+				delete(running, name)
+			}
+		}
+		for name := range wanted {
+			if running[name] != nil {
+				continue
+			}
+			botCtx, cancel := context.WithCancel(ctx)
+			running[name] = cancel
+			bot := New(name, m.store, m.watchlist, m.tracker, m.notifiers)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bot.Run(botCtx)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package tokenmeta
+
+import (
+	token "Intermediate_web3/internal/build"
+	"Intermediate_web3/internal/models"
+	"Intermediate_web3/internal/rpcpool"
+	"Intermediate_web3/internal/store"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// bytes32ABI is the name and symbol of legacy tokens such as MKR, which
+// return them as bytes32 instead of string.
+const bytes32ABI = `[
+	{"inputs":[],"name":"name","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},
+	{"inputs":[],"name":"symbol","outputs":[{"name":"","type":"bytes32"}],"stateMutability":"view","type":"function"}
+]`
+
+var bytes32Metadata = mustParseABI(bytes32ABI)
+
+// ErrNotToken is returned by Resolve when the contract does not answer as an
+// ERC-20 token, e.g. when decimals() reverts or is missing. Other errors are
+// failures to reach the chain or the store, which may pass when retried.
+var ErrNotToken = errors.New("not an ERC-20 token")
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse ABI: %v", err))
+	}
+	return parsed
+}
+
+// Service resolves the name, symbol and decimals of token contracts. A token
+// is read from the chain the first time it is seen, then served from the
+// store and from memory. It is safe for concurrent use.
+type Service struct {
+	store store.TrackingStore
+	mu    sync.RWMutex
+	// cache maps chain/address to the metadata of the token.
+	cache map[string]models.Token
+}
+
+func NewService(trackingStore store.TrackingStore) *Service {
+	return &Service{
+		store: trackingStore,
+		cache: make(map[string]models.Token),
+	}
+}
+
+// Resolve returns the metadata of the token of chain at address, reading it
+// through caller if it is not known yet.
+func (s *Service) Resolve(ctx context.Context, chain string, caller bind.ContractCaller, address common.Address) (models.Token, error) {
+	tokenAddress := strings.ToLower(address.Hex())
+	key := chain + "/" + tokenAddress
+
+	s.mu.RLock()
+	metadata, ok := s.cache[key]
+	s.mu.RUnlock()
+	if ok {
+		return metadata, nil
+	}
+
+	stored, found, err := s.store.GetToken(ctx, chain, tokenAddress)
+	if err != nil {
+		return models.Token{}, err
+	}
+	if found {
+		metadata = *stored
+	} else {
+		metadata, err = fetch(ctx, caller, address)
+		if err != nil {
+			return models.Token{}, err
+		}
+		metadata.Chain = chain
+		metadata.Address = tokenAddress
+		err = s.store.SaveToken(ctx, &metadata)
+		if err != nil {
+			return models.Token{}, err
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[key] = metadata
+	s.mu.Unlock()
+	return metadata, nil
+}
+
+// fetch reads the metadata of a token from the chain. The name is optional,
+// as a few tokens do not implement it.
+func fetch(ctx context.Context, caller bind.ContractCaller, address common.Address) (models.Token, error) {
+	node := &nodeCaller{ContractCaller: caller}
+	caller = node
+	contract, err := token.NewStoreCaller(address, caller)
+	if err != nil {
+		return models.Token{}, fmt.Errorf("failed to create token contract: %v", err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	decimals, err := contract.Decimals(opts)
+	if err != nil {
+		return models.Token{}, node.classify(ctx, fmt.Errorf("failed to get token decimals: %v", err))
+	}
+	symbol, err := contract.Symbol(opts)
+	if err != nil {
+		symbol, err = callBytes32(opts, caller, address, "symbol")
+		if err != nil {
+			return models.Token{}, node.classify(ctx, fmt.Errorf("failed to get token symbol: %v", err))
+		}
+	}
+	name, err := contract.Name(opts)
+	if err != nil {
+		name, err = callBytes32(opts, caller, address, "name")
+		if err != nil {
+			name = ""
+		}
+	}
+	return models.Token{Name: name, Symbol: symbol, Decimals: decimals}, nil
+}
+
+// callBytes32 calls a method returning a bytes32 string, padded with zeros.
+func callBytes32(opts *bind.CallOpts, caller bind.ContractCaller, address common.Address, method string) (string, error) {
+	contract := bind.NewBoundContract(address, bytes32Metadata, caller, nil, nil)
+	var out []interface{}
+	err := contract.Call(opts, &out, method)
+	if err != nil {
+		return "", err
+	}
+	value := *abi.ConvertType(out[0], new([32]byte)).(*[32]byte)
+	return string(bytes.TrimRight(value[:], "\x00")), nil
+}
+
+// nodeCaller remembers the last error of the node, to tell the node failing
+// from a contract answering that it is not a token.
+type nodeCaller struct {
+	bind.ContractCaller
+	err error
+}
+
+func (c *nodeCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	code, err := c.ContractCaller.CodeAt(ctx, contract, blockNumber)
+	c.err = err
+	return code, err
+}
+
+func (c *nodeCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	output, err := c.ContractCaller.CallContract(ctx, call, blockNumber)
+	c.err = err
+	return output, err
+}
+
+// classify marks err with ErrNotToken when the contract answered: the call
+// reverted, there is no contract, or its answer does not decode. Failures of
+// the node itself are left as they are, to be retried.
+func (c *nodeCaller) classify(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	if c.err != nil {
+		var rpcErr gethrpc.Error
+		if !errors.As(c.err, &rpcErr) || rpcpool.IsTransient(c.err) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrNotToken, err)
+}
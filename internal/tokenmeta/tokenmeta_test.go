@@ -0,0 +1,63 @@
+package tokenmeta
+
+import (
+	"Intermediate_web3/internal/store"
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+type rpcError struct {
+	message string
+	code    int
+}
+
+func (e rpcError) Error() string  { return e.message }
+func (e rpcError) ErrorCode() int { return e.code }
+
+// fakeCaller answers every call with output and err, and has code at every
+// address unless noCode.
+type fakeCaller struct {
+	output []byte
+	err    error
+	noCode bool
+}
+
+func (c fakeCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if c.noCode {
+		return nil, nil
+	}
+	return []byte{1}, nil
+}
+
+func (c fakeCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return c.output, c.err
+}
+
+func TestResolveTellsNonTokensFromFailures(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		caller   fakeCaller
+		notToken bool
+	}{
+		{name: "reverted", caller: fakeCaller{err: rpcError{message: "execution reverted", code: 3}}, notToken: true},
+		{name: "no contract", caller: fakeCaller{noCode: true}, notToken: true},
+		{name: "no decimals", caller: fakeCaller{output: []byte{}}, notToken: true},
+		{name: "rate limited", caller: fakeCaller{err: rpcError{message: "limit exceeded", code: -32005}}},
+		{name: "unreachable", caller: fakeCaller{err: errors.New("connection refused")}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			service := NewService(store.NewMemory())
+			_, err := service.Resolve(context.Background(), "eth", test.caller, common.HexToAddress("0xcc"))
+			if err == nil {
+				t.Fatal("Resolve succeeded, want an error")
+			}
+			if errors.Is(err, ErrNotToken) != test.notToken {
+				t.Errorf("Resolve = %v, want ErrNotToken %v", err, test.notToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package watchlist
+
+import (
+	"Intermediate_web3/internal/models"
+	"strings"
+	"sync"
+)
+
+// Registry holds the watched wallets and tracked tokens of every configured
+// chain, merged from the config file and the database. It is safe for
+// concurrent use: the API changes it while the trackers read it, so changes
+// apply from the next block on. Addresses are lowercased.
+type Registry struct {
+	mu     sync.RWMutex
+	chains map[string]*chainList
+}
+
+// chainList maps addresses to their labels. Stored entries come from the
+// database and take precedence over the config file, unless their label is
+// empty.
+type chainList struct {
+	configWallets map[string]string
+	configTokens  map[string]string
+	storedWallets map[string]string
+	storedTokens  map[string]string
+}
+
+func New() *Registry {
+	return &Registry{chains: make(map[string]*chainList)}
+}
+
+// SetConfig replaces the entries of the config file for a chain, adding the
+// chain if it is new.
+func (r *Registry) SetConfig(chainConfig models.ChainConfig) {
+	configWallets := make(map[string]string)
+	for _, wallet := range chainConfig.Wallets {
+		configWallets[strings.ToLower(wallet.Address)] = wallet.Label
+	}
+	configTokens := make(map[string]string)
+	for _, tokenAddress := range chainConfig.ListTokensTracking {
+		address := strings.ToLower(tokenAddress)
+		configTokens[address] = chainConfig.TrackingTokensConfig[address].TokenName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := r.chain(chainConfig.Chain)
+	list.configWallets = configWallets
+	list.configTokens = configTokens
+}
+
+// RemoveChain forgets a chain that is no longer configured.
+func (r *Registry) RemoveChain(chain string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.chains, chain)
+}
+
+// SetStored replaces the database entries of a chain.
+func (r *Registry) SetStored(chain string, wallets []models.Wallet, tokens []models.TrackedToken) {
+	storedWallets := make(map[string]string)
+	for _, wallet := range wallets {
+		storedWallets[strings.ToLower(wallet.Address)] = wallet.Label
+	}
+	storedTokens := make(map[string]string)
+	for _, trackedToken := range tokens {
+		storedTokens[strings.ToLower(trackedToken.Address)] = trackedToken.Label
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := r.chain(chain)
+	list.storedWallets = storedWallets
+	list.storedTokens = storedTokens
+}
+
+// chain returns the list of a chain, creating it. r.mu must be held.
+func (r *Registry) chain(chain string) *chainList {
+	list, ok := r.chains[chain]
+	if !ok {
+		list = &chainList{
+			configWallets: make(map[string]string),
+			configTokens:  make(map[string]string),
+			storedWallets: make(map[string]string),
+			storedTokens:  make(map[string]string),
+		}
+		r.chains[chain] = list
+	}
+	return list
+}
+
+// HasChain reports whether chain is a configured chain.
+func (r *Registry) HasChain(chain string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.chains[chain]
+	return ok
+}
+
+func (r *Registry) SetWallet(chain string, address string, label string) {
+	r.set(chain, func(list *chainList) map[string]string { return list.storedWallets }, address, label)
+}
+
+func (r *Registry) RemoveWallet(chain string, address string) {
+	r.remove(chain, func(list *chainList) map[string]string { return list.storedWallets }, address)
+}
+
+func (r *Registry) SetToken(chain string, address string, label string) {
+	r.set(chain, func(list *chainList) map[string]string { return list.storedTokens }, address, label)
+}
+
+func (r *Registry) RemoveToken(chain string, address string) {
+	r.remove(chain, func(list *chainList) map[string]string { return list.storedTokens }, address)
+}
+
+func (r *Registry) set(chain string, entries func(*chainList) map[string]string, address string, label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list, ok := r.chains[chain]
+	if !ok {
+		return
+	}
+	entries(list)[strings.ToLower(address)] = label
+}
+
+func (r *Registry) remove(chain string, entries func(*chainList) map[string]string, address string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list, ok := r.chains[chain]
+	if !ok {
+		return
+	}
+	delete(entries(list), strings.ToLower(address))
+}
+
+// WalletLabel returns the label of a watched wallet, and whether the address
+// is watched at all.
+func (r *Registry) WalletLabel(chain string, address string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list, ok := r.chains[chain]
+	if !ok {
+		return "", false
+	}
+	return lookup(list.configWallets, list.storedWallets, strings.ToLower(address))
+}
+
+// IsToken reports whether the token at address is tracked.
+func (r *Registry) IsToken(chain string, address string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list, ok := r.chains[chain]
+	if !ok {
+		return false
+	}
+	_, ok = lookup(list.configTokens, list.storedTokens, strings.ToLower(address))
+	return ok
+}
+
+// Wallets returns the addresses of the watched wallets of a chain.
+func (r *Registry) Wallets(chain string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list, ok := r.chains[chain]
+	if !ok {
+		return nil
+	}
+	return merge(list.configWallets, list.storedWallets)
+}
+
+// Tokens returns the addresses of the tracked tokens of a chain.
+func (r *Registry) Tokens(chain string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list, ok := r.chains[chain]
+	if !ok {
+		return nil
+	}
+	return merge(list.configTokens, list.storedTokens)
+}
+
+func lookup(configured, stored map[string]string, address string) (string, bool) {
+	label, ok := stored[address]
+	if ok && label != "" {
+		return label, true
+	}
+	configLabel, found := configured[address]
+	if found {
+		return configLabel, true
+	}
+	return label, ok
+}
+
+func merge(configured, stored map[string]string) []string {
+	addresses := make([]string, 0, len(configured)+len(stored))
+	for address := range configured {
+		addresses = append(addresses, address)
+	}
+	for address := range stored {
+		if _, ok := configured[address]; !ok {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}